@@ -27,7 +27,7 @@ var usage = `mainframe — terminal.
 
 Usage:
   terminal -h | --help
-  terminal [options] [-s=<socket>] listen
+  terminal [options] [--font=<path>]... [-s=<socket>] listen
   terminal [options] [-s=<socket>] open [--open-args=] -- <command>...
 
 Options:
@@ -35,20 +35,55 @@ Options:
   -s --socket <socket>   Path to control socket.
                           [default: /tmp/mainframe.sock]
   --profile <path>       Write CPU profile to specified file.
+  --headless             Don't open windows on display, composite them in
+                          memory instead. Useful for running without GPU.
   --open-args <options>  Parameters for new window in text protocol format.
-  --font <path>          Font file to use.
+  --font <path>          Font file to use. Can be specified several times,
+                          then following fonts are used for chars which
+                          are missing in preceding fonts.
+  --font-bold <path>     Font file to use for bold text.
+  --font-italic <path>   Font file to use for italic text.
+  --font-bold-italic <path>
+                         Font file to use for bold italic text.
   --font-size <size>     Font size to use in points. [default: 14]
   --font-dpi <dpi>       Screen DPI to render font for. [default: 72]
+  --line-height <percent>
+                         Height of cell in percents of font line height.
+                          [default: 100]
+  --letter-spacing <pixels>
+                         Pixels added to width of cell, can be negative.
+                          [default: 0]
+  --font-format <format>
+                         Format of first --font file: truetype,
+                          opentype, bdf or pcf. Detected by file
+                          extension if not specified. Other font files
+                          are always detected by extension.
+  --no-font-cache        Don't cache rasterized glyphs on disk between
+                          restarts.
+  --no-builtin-glyphs    Use font outlines for box-drawing, block element
+                          and braille chars instead of drawing them to
+                          fill cells exactly.
 `
 
 type Opts struct {
 	Socket string `docopt:"--socket"`
 
-	Listen bool `docopt:"listen"`
+	Listen   bool `docopt:"listen"`
+	Headless bool `docopt:"--headless"`
 
-	Font     string  `docopt:"--font"`
-	FontDPI  float64 `docopt:"--font-dpi"`
-	FontSize float64 `docopt:"--font-size"`
+	Font           []string `docopt:"--font"`
+	FontBold       string   `docopt:"--font-bold"`
+	FontItalic     string   `docopt:"--font-italic"`
+	FontBoldItalic string   `docopt:"--font-bold-italic"`
+	FontDPI        float64  `docopt:"--font-dpi"`
+	FontSize       float64  `docopt:"--font-size"`
+	FontFormat     string   `docopt:"--font-format"`
+
+	LineHeight    int `docopt:"--line-height"`
+	LetterSpacing int `docopt:"--letter-spacing"`
+
+	NoFontCache     bool `docopt:"--no-font-cache"`
+	NoBuiltinGlyphs bool `docopt:"--no-builtin-glyphs"`
 
 	Profile string `docopt:"--profile"`
 
@@ -92,21 +127,46 @@ func main() {
 }
 
 func listen(opts Opts) {
-	if opts.Font == "" {
+	if len(opts.Font) == 0 {
 		log.Fatal("font file should be specified")
 	}
 
-	font, err := fonts.Load(
-		opts.Font,
+	if opts.LineHeight <= 0 {
+		log.Fatal("line height should be greater than zero")
+	}
+
+	options := []interface{}{
 		fonts.FontDPI(opts.FontDPI),
 		fonts.FontSize(opts.FontSize),
 		fonts.FontHinting(true),
-	)
+		fonts.FontBold(opts.FontBold),
+		fonts.FontItalic(opts.FontItalic),
+		fonts.FontBoldItalic(opts.FontBoldItalic),
+		fonts.FontFormat(opts.FontFormat),
+		fonts.FontBuiltinGlyphs(!opts.NoBuiltinGlyphs),
+		fonts.FontLineHeight(opts.LineHeight),
+		fonts.FontLetterSpacing(opts.LetterSpacing),
+	}
+
+	if !opts.NoFontCache {
+		options = append(options, fonts.FontCacheDir(fonts.DefaultCacheDir()))
+	}
+
+	for _, fallback := range opts.Font[1:] {
+		options = append(options, fonts.FontFallback(fallback))
+	}
+
+	font, err := fonts.Load(opts.Font[0], options...)
 	if err != nil {
 		panic(err)
 	}
 
-	engine := engine.New()
+	backend := engine.NewGLBackend()
+	if opts.Headless {
+		backend = engine.NewHeadlessBackend()
+	}
+
+	engine := engine.New(backend)
 
 	listener, err := server.Listen(opts.Socket, engine)
 	if err != nil {
@@ -0,0 +1,134 @@
+package engine
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/seletskiy/mainframe/pkg/fonts"
+	"github.com/seletskiy/mainframe/pkg/protocol/messages"
+)
+
+// Backend displays windows and delivers their input to engine. Engine owns
+// screens, fonts and clients, while backend owns windows and everything
+// which is needed to draw screen into window.
+//
+// All methods except Wakeup are called from main engine thread.
+type Backend interface {
+	// Init is called once before any window is created.
+	Init() error
+
+	// Terminate is called once when engine stops.
+	Terminate()
+
+	// CreateWindow creates window of given size in pixels. Events of window
+	// are reported via given callbacks.
+	CreateWindow(
+		width int,
+		height int,
+		options *messages.Open,
+		callbacks WindowCallbacks,
+	) (Window, error)
+
+	// GetMonitorSize returns size of primary monitor in pixels.
+	GetMonitorSize() (int, int)
+
+	// Wait blocks until there are window events or Wakeup is called.
+	Wait()
+
+	// Wakeup interrupts Wait. It can be called from any thread.
+	Wakeup()
+
+	// FreeFont releases resources allocated for font, which is not used by
+	// any window anymore.
+	FreeFont(font *fonts.Font)
+
+	// Free releases resources shared by windows when last window is
+	// closed.
+	Free()
+}
+
+// Window is window created by backend.
+type Window interface {
+	// GetSize returns size of window in screen coordinates, which is used
+	// to resize window.
+	GetSize() (int, int)
+
+	// GetFramebufferSize returns size of window in pixels, which differs
+	// from window size on HiDPI monitors.
+	GetFramebufferSize() (int, int)
+
+	// GetScale returns content scale of monitor window is displayed on.
+	GetScale() float64
+
+	SetSize(width, height int)
+	SetPos(x, y int)
+
+	// Close marks window as closed, so it's destroyed on next render.
+	Close()
+	ShouldClose() bool
+	Destroy()
+
+	// Present draws given screen into window. Screen is locked.
+	Present(
+		screen *Screen,
+		foreground color.RGBA,
+		background color.RGBA,
+		palette Palette,
+	) error
+
+	// Capture returns image of last presented screen, which has size of
+	// framebuffer and contains premultiplied colors.
+	Capture() (*image.RGBA, error)
+}
+
+// WindowCallbacks are called by backend on window events.
+type WindowCallbacks struct {
+	Key     func(Key)
+	Input   func(rune, Mods)
+	Rescale func(float64)
+	Refresh func()
+}
+
+// Action is state of key reported in key event.
+type Action int
+
+const (
+	ActionPress Action = iota
+	ActionRelease
+	ActionRepeat
+)
+
+// Mods is set of modifier keys which are held during key event.
+type Mods int
+
+const (
+	ModShift Mods = 1 << iota
+	ModControl
+	ModAlt
+	ModSuper
+)
+
+// Zoom is zoom step which is requested by key, like Ctrl+Plus.
+type Zoom int
+
+const (
+	ZoomNone Zoom = iota
+	ZoomIn
+	ZoomOut
+	ZoomReset
+)
+
+// Key is key event reported by backend. Backend maps its keys to symbols,
+// like `Ctrl+Q`, and recognizes zoom keys, so engine doesn't depend on
+// key codes of backend.
+type Key struct {
+	Action Action
+	Mods   Mods
+
+	Symbol string
+
+	// Code is platform-specific scancode of key.
+	Code int
+
+	Zoom Zoom
+}
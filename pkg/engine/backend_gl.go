@@ -0,0 +1,967 @@
+//go:build !headless
+// +build !headless
+
+package engine
+
+import (
+	"image"
+	"image/color"
+	"runtime"
+	"strings"
+	"unsafe"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/glfw/v3.3/glfw"
+	"github.com/reconquest/karma-go"
+	"github.com/seletskiy/mainframe/pkg/fonts"
+	"github.com/seletskiy/mainframe/pkg/log"
+	"github.com/seletskiy/mainframe/pkg/protocol/messages"
+)
+
+// TODO: move go-gl to vendor!
+
+// #include <X11/Xlib.h>
+// #include "../../../../go-gl/glfw/v3.3/glfw/glfw/include/GLFW/glfw3.h"
+// #cgo linux LDFLAGS: -lX11
+import "C"
+
+// glBackend displays windows via GLFW and draws cells via OpenGL shaders.
+type glBackend struct {
+	vertices struct {
+		points []float32
+
+		buffers struct {
+			triangles  uint32
+			glyphs     uint32
+			attributes uint32
+			colors     uint32
+		}
+	}
+
+	shaders struct {
+		program  uint32
+		vertex   uint32
+		fragment uint32
+	}
+
+	// textures holds atlas texture for every font used by windows.
+	textures map[*fonts.Font]*fontTexture
+
+	// windows share GL context with each other, so buffers, shaders and
+	// textures are created only once.
+	windows map[*glWindow]bool
+}
+
+type glWindow struct {
+	*glfw.Window
+
+	backend *glBackend
+
+	vao uint32
+
+	// show is set for windows which are created hidden to be moved or
+	// configured before displaying, they are shown on first present.
+	show bool
+}
+
+type fontTexture struct {
+	id uint32
+
+	// size of font atlas which is uploaded into texture.
+	size image.Point
+}
+
+// NewGLBackend creates backend which draws windows on display using GPU.
+func NewGLBackend() Backend {
+	backend := &glBackend{}
+	backend.textures = map[*fonts.Font]*fontTexture{}
+	backend.windows = map[*glWindow]bool{}
+
+	return backend
+}
+
+func (backend *glBackend) Init() error {
+	// Required for OpenGL to work.
+	runtime.LockOSThread()
+
+	err := gl.Init()
+	if err != nil {
+		return karma.Format(
+			err,
+			"{gl} unable to init",
+		)
+	}
+
+	err = glfw.Init()
+	if err != nil {
+		return karma.Format(
+			err,
+			"{glfw} unable to init",
+		)
+	}
+
+	return nil
+}
+
+func (backend *glBackend) Terminate() {
+	glfw.Terminate()
+}
+
+func (backend *glBackend) CreateWindow(
+	width int,
+	height int,
+	options *messages.Open,
+	callbacks WindowCallbacks,
+) (Window, error) {
+	glfw.WindowHint(glfw.ContextVersionMajor, 4)
+	glfw.WindowHint(glfw.ContextVersionMinor, 1)
+	glfw.WindowHint(glfw.OpenGLProfile, glfw.OpenGLCoreProfile)
+	glfw.WindowHint(glfw.OpenGLForwardCompatible, glfw.True)
+	glfw.WindowHint(glfw.DoubleBuffer, glfw.False)
+
+	var position bool
+
+	if options.X != nil && options.Y != nil {
+		position = true
+	}
+
+	// FIXME: hidden has no use now, because there is no way to show hidden
+	// window from mainframe API.
+	if options.Hidden || options.Raw || position {
+		glfw.WindowHint(glfw.Visible, glfw.False)
+	} else {
+		glfw.WindowHint(glfw.Visible, glfw.True)
+	}
+
+	// FIXME: not really works with i3 for the reason it doesn't check for
+	// NET_WM_STATE_ABOVE when setting floating mode:
+	//
+	// https://github.com/i3/i3/blob/next/src/manage.c#L439
+	//if options.Floating {
+	//    glfw.WindowHint(glfw.Floating, glfw.True)
+	//} else {
+	//    glfw.WindowHint(glfw.Floating, glfw.False)
+	//}
+
+	if options.Fixed {
+		glfw.WindowHint(glfw.Resizable, glfw.False)
+	} else {
+		glfw.WindowHint(glfw.Resizable, glfw.True)
+	}
+
+	if options.Bare {
+		glfw.WindowHint(glfw.Decorated, glfw.False)
+	} else {
+		glfw.WindowHint(glfw.Decorated, glfw.True)
+	}
+
+	// Transparency will take effect only if compositor is running.
+	if options.Transparent {
+		glfw.WindowHint(glfw.TransparentFramebuffer, glfw.True)
+	} else {
+		glfw.WindowHint(glfw.TransparentFramebuffer, glfw.False)
+	}
+
+	var parent *glfw.Window
+	for window := range backend.windows {
+		parent = window.Window
+		break
+	}
+
+	handle, err := glfw.CreateWindow(
+		width,
+		height,
+		options.Title,
+		nil,
+		parent,
+	)
+	if err != nil {
+		return nil, karma.Format(
+			err,
+			"{glfw} unable to create window",
+		)
+	}
+
+	if position {
+		handle.SetPos(*options.X, *options.Y)
+	}
+
+	if options.Raw {
+		overrideRedirect(handle)
+	}
+
+	window := &glWindow{
+		Window:  handle,
+		backend: backend,
+		show:    !options.Hidden && (options.Raw || position),
+	}
+
+	handle.SetCharModsCallback(
+		func(
+			_ *glfw.Window,
+			char rune,
+			mods glfw.ModifierKey,
+		) {
+			callbacks.Input(char, getMods(mods))
+		},
+	)
+
+	handle.SetKeyCallback(
+		func(
+			_ *glfw.Window,
+			key glfw.Key,
+			scancode int,
+			action glfw.Action,
+			mods glfw.ModifierKey,
+		) {
+			callbacks.Key(Key{
+				Action: getAction(action),
+				Mods:   getMods(mods),
+				Symbol: MapKeyToSymbol(key, mods),
+				Code:   scancode,
+				Zoom:   getZoom(key, mods),
+			})
+		},
+	)
+
+	handle.SetContentScaleCallback(
+		func(
+			_ *glfw.Window,
+			scale float32,
+			_ float32,
+		) {
+			callbacks.Rescale(float64(scale))
+		},
+	)
+
+	handle.SetRefreshCallback(
+		func(
+			_ *glfw.Window,
+		) {
+			callbacks.Refresh()
+		},
+	)
+
+	//handle.SetCloseCallback(
+	//    func(
+	//        _ *glfw.Window,
+	//    ) {
+	//        fmt.Fprintln(os.Stderr, "XXXXXX engine.go:308  CLOSE")
+	//    },
+	//)
+
+	handle.MakeContextCurrent()
+
+	glfw.SwapInterval(0)
+
+	gl.Enable(gl.DEBUG_OUTPUT)
+	gl.DebugMessageCallback(backend.debug, nil)
+	gl.GenVertexArrays(1, &window.vao)
+
+	backend.windows[window] = true
+
+	return window, nil
+}
+
+// GetMonitorSize returns size of primary monitor in pixels.
+func (backend *glBackend) GetMonitorSize() (int, int) {
+	mode := glfw.GetPrimaryMonitor().GetVideoMode()
+
+	return mode.Width, mode.Height
+}
+
+func (backend *glBackend) Wait() {
+	glfw.WaitEvents()
+}
+
+func (backend *glBackend) Wakeup() {
+	glfw.PostEmptyEvent()
+}
+
+func (backend *glBackend) FreeFont(font *fonts.Font) {
+	texture, ok := backend.textures[font]
+	if !ok {
+		return
+	}
+
+	gl.DeleteTextures(1, &texture.id)
+	delete(backend.textures, font)
+}
+
+func (backend *glBackend) Free() {
+	gl.DeleteBuffers(1, &backend.vertices.buffers.triangles)
+	gl.DeleteBuffers(1, &backend.vertices.buffers.glyphs)
+	gl.DeleteBuffers(1, &backend.vertices.buffers.attributes)
+	gl.DeleteBuffers(1, &backend.vertices.buffers.colors)
+	gl.DeleteShader(backend.shaders.vertex)
+	gl.DeleteShader(backend.shaders.fragment)
+	gl.DeleteProgram(backend.shaders.program)
+
+	for font := range backend.textures {
+		backend.FreeFont(font)
+	}
+
+	backend.vertices.points = nil
+	backend.vertices.buffers.triangles = 0
+	backend.vertices.buffers.glyphs = 0
+	backend.vertices.buffers.attributes = 0
+	backend.shaders.vertex = 0
+	backend.shaders.fragment = 0
+	backend.shaders.program = 0
+}
+
+func (window *glWindow) GetScale() float64 {
+	scale, _ := window.GetContentScale()
+
+	return float64(scale)
+}
+
+func (window *glWindow) Close() {
+	window.SetShouldClose(true)
+}
+
+func (window *glWindow) Destroy() {
+	delete(window.backend.windows, window)
+
+	window.Window.Destroy()
+}
+
+func (window *glWindow) Present(
+	screen *Screen,
+	foreground color.RGBA,
+	background color.RGBA,
+	palette Palette,
+) error {
+	backend := window.backend
+
+	window.MakeContextCurrent()
+
+	err := backend.initShaders()
+	if err != nil {
+		return err
+	}
+
+	err = backend.initVertices()
+	if err != nil {
+		return err
+	}
+
+	backend.initTextures(screen.font)
+
+	gl.BindVertexArray(window.vao)
+
+	gl.Enable(gl.BLEND)
+
+	// Alpha is accumulated separately, so resulting framebuffer contains
+	// premultiplied colors, which are expected by compositors.
+	gl.BlendFuncSeparate(
+		gl.SRC_ALPHA,
+		gl.ONE_MINUS_SRC_ALPHA,
+		gl.ONE,
+		gl.ONE_MINUS_SRC_ALPHA,
+	)
+
+	gl.ClearColor(
+		float32(background.R)/0xff,
+		float32(background.G)/0xff,
+		float32(background.B)/0xff,
+		float32(background.A)/0xff,
+	)
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+
+	var (
+		windowWidth, windowHeight = window.GetFramebufferSize()
+
+		glyphWidth  = screen.font.GetWidth()
+		glyphHeight = screen.font.GetHeight()
+	)
+
+	gl.Viewport(0, 0, int32(windowWidth), int32(windowHeight))
+
+	gl.Uniform2i(0, int32(windowWidth), int32(windowHeight))
+	gl.Uniform2i(1, int32(glyphWidth), int32(glyphHeight))
+
+	gl.Uniform1i(
+		backend.getUniform("uni_Baseline"),
+		int32(screen.font.GetBaseline()),
+	)
+
+	backend.setUniformColor("uni_Foreground", foreground)
+	backend.setUniformColor("uni_Background", background)
+
+	// Palette is passed as array of uvec4, so every array item holds 4
+	// palette colors.
+	gl.Uniform4uiv(
+		backend.getUniform("uni_Palette"),
+		int32(len(palette)/4),
+		&palette.Pack()[0],
+	)
+
+	gl.BindBuffer(gl.ARRAY_BUFFER, backend.vertices.buffers.triangles)
+	gl.VertexAttribPointer(0, 2, gl.FLOAT, false, 2*4, gl.PtrOffset(0))
+	gl.EnableVertexAttribArray(0)
+
+	gl.BindBuffer(gl.ARRAY_BUFFER, backend.vertices.buffers.glyphs)
+	gl.BufferData(
+		gl.ARRAY_BUFFER,
+		4*len(screen.GetCells()),
+		gl.Ptr(screen.GetCells()),
+		gl.DYNAMIC_DRAW,
+	)
+	gl.VertexAttribIPointer(1, 2, gl.INT, 2*4, gl.PtrOffset(0))
+	gl.EnableVertexAttribArray(1)
+	gl.VertexAttribDivisor(1, 1)
+
+	gl.BindBuffer(gl.ARRAY_BUFFER, backend.vertices.buffers.attributes)
+	gl.BufferData(
+		gl.ARRAY_BUFFER,
+		4*len(screen.GetAttrs()),
+		gl.Ptr(screen.GetAttrs()),
+		gl.DYNAMIC_DRAW,
+	)
+	gl.VertexAttribIPointer(2, 1, gl.INT, 1*4, gl.PtrOffset(0))
+	gl.EnableVertexAttribArray(2)
+	gl.VertexAttribDivisor(2, 1)
+
+	gl.BindBuffer(gl.ARRAY_BUFFER, backend.vertices.buffers.colors)
+	gl.BufferData(
+		gl.ARRAY_BUFFER,
+		4*len(screen.GetColors()),
+		gl.Ptr(screen.GetColors()),
+		gl.DYNAMIC_DRAW,
+	)
+	gl.VertexAttribIPointer(3, 2, gl.INT, 2*4, gl.PtrOffset(0))
+	gl.EnableVertexAttribArray(3)
+	gl.VertexAttribDivisor(3, 1)
+
+	gl.DrawArraysInstanced(
+		gl.TRIANGLE_STRIP,
+		0,
+		6,
+		int32(screen.GetArea()),
+	)
+
+	gl.Finish()
+
+	if window.show {
+		window.show = false
+		window.Show()
+	}
+
+	return nil
+}
+
+// Capture reads window framebuffer. Window is single-buffered, so
+// framebuffer holds last presented screen.
+func (window *glWindow) Capture() (*image.RGBA, error) {
+	window.MakeContextCurrent()
+
+	var (
+		width, height = window.GetFramebufferSize()
+
+		frame = image.NewRGBA(image.Rect(0, 0, width, height))
+	)
+
+	if frame.Rect.Empty() {
+		return frame, nil
+	}
+
+	gl.PixelStorei(gl.PACK_ALIGNMENT, 1)
+	gl.ReadPixels(
+		0,
+		0,
+		int32(width),
+		int32(height),
+		gl.RGBA,
+		gl.UNSIGNED_BYTE,
+		gl.Ptr(frame.Pix),
+	)
+
+	// Rows are read starting from bottom of framebuffer.
+	row := make([]uint8, frame.Stride)
+
+	for y := 0; y < height/2; y++ {
+		var (
+			top    = y * frame.Stride
+			bottom = (height - 1 - y) * frame.Stride
+
+			upper = frame.Pix[top : top+frame.Stride]
+			lower = frame.Pix[bottom : bottom+frame.Stride]
+		)
+
+		copy(row, upper)
+		copy(upper, lower)
+		copy(lower, row)
+	}
+
+	return frame, nil
+}
+
+func (backend *glBackend) setUniformColor(name string, value color.RGBA) {
+	gl.Uniform4f(
+		backend.getUniform(name),
+		float32(value.R)/0xff,
+		float32(value.G)/0xff,
+		float32(value.B)/0xff,
+		float32(value.A)/0xff,
+	)
+}
+
+func (backend *glBackend) getUniform(name string) int32 {
+	return gl.GetUniformLocation(backend.shaders.program, gl.Str(name+"\x00"))
+}
+
+func (backend *glBackend) initShaders() error {
+	if backend.shaders.program > 0 {
+		gl.UseProgram(backend.shaders.program)
+
+		return nil
+	}
+
+	var err error
+
+	backend.shaders.vertex, err = backend.compileShader(
+		gl.VERTEX_SHADER,
+		vertexShader,
+	)
+	if err != nil {
+		return karma.Format(
+			err,
+			"{gl} unable to compile vertex shader",
+		)
+	}
+
+	backend.shaders.fragment, err = backend.compileShader(
+		gl.FRAGMENT_SHADER,
+		fragmentShader,
+	)
+	if err != nil {
+		return karma.Format(
+			err,
+			"{gl} unable to compile fragment shader",
+		)
+	}
+
+	backend.shaders.program = gl.CreateProgram()
+
+	gl.AttachShader(backend.shaders.program, backend.shaders.vertex)
+	gl.AttachShader(backend.shaders.program, backend.shaders.fragment)
+	gl.LinkProgram(backend.shaders.program)
+	gl.UseProgram(backend.shaders.program)
+
+	return nil
+}
+
+func (backend *glBackend) initVertices() error {
+	if backend.vertices.points != nil {
+		return nil
+	}
+
+	//         ->
+	// (0; 1) X--X (1; 1)
+	//      ^ |\ | |
+	//      | | \| V
+	// (0; 0) X--X (1; 0)
+	//         <-
+	backend.vertices.points = []float32{
+		0, 0,
+		0, 1,
+		1, 0,
+
+		1, 0,
+		0, 1,
+		1, 1,
+	}
+
+	// First buffer for vertices, which form triangles, which form cells.
+	gl.GenBuffers(1, &backend.vertices.buffers.triangles)
+
+	gl.BindBuffer(gl.ARRAY_BUFFER, backend.vertices.buffers.triangles)
+
+	gl.BufferData(
+		gl.ARRAY_BUFFER,
+		len(backend.vertices.points)*4,
+		gl.Ptr(backend.vertices.points),
+		gl.STATIC_DRAW,
+	)
+
+	// Second buffer for vertices data, in this case it's glyph coordinates
+	// in font.
+	gl.GenBuffers(1, &backend.vertices.buffers.glyphs)
+
+	// Third buffer for vertices data, in this case it's cell attributes.
+	gl.GenBuffers(1, &backend.vertices.buffers.attributes)
+
+	// Fourth buffer for background/foreground colors.
+	gl.GenBuffers(1, &backend.vertices.buffers.colors)
+
+	return nil
+}
+
+func (backend *glBackend) initTextures(font *fonts.Font) error {
+	// Glyphs are added to font atlas on first use, so texture should be
+	// updated before rendering.
+	font.Lock()
+	defer font.Unlock()
+
+	var (
+		changes = font.Flush()
+		size    = font.Image.Bounds().Size()
+	)
+
+	texture, ok := backend.textures[font]
+	if ok {
+		gl.BindTexture(gl.TEXTURE_2D, texture.id)
+
+		// Atlas image is reallocated when it grows, so texture should be
+		// uploaded entirely.
+		if texture.size == size {
+			for _, region := range changes {
+				backend.updateTexture(font.Image, region)
+			}
+
+			return nil
+		}
+	} else {
+		texture = &fontTexture{}
+
+		gl.GenTextures(1, &texture.id)
+		gl.BindTexture(gl.TEXTURE_2D, texture.id)
+
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_BORDER)
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_R, gl.CLAMP_TO_BORDER)
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.NEAREST)
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.NEAREST)
+
+		backend.textures[font] = texture
+	}
+
+	texture.size = size
+
+	gl.TexImage2D(
+		gl.TEXTURE_2D,
+		0,
+		gl.RGBA,
+		int32(size.X),
+		int32(size.Y),
+		0,
+		gl.RGBA,
+		gl.UNSIGNED_BYTE,
+		gl.Ptr(font.Image.Pix),
+	)
+
+	return nil
+}
+
+// updateTexture uploads only given region of atlas image into currently
+// bound texture.
+func (backend *glBackend) updateTexture(
+	atlas *image.RGBA,
+	region image.Rectangle,
+) {
+	region = region.Intersect(atlas.Bounds())
+	if region.Empty() {
+		return
+	}
+
+	gl.PixelStorei(gl.UNPACK_ROW_LENGTH, int32(atlas.Bounds().Dx()))
+	defer gl.PixelStorei(gl.UNPACK_ROW_LENGTH, 0)
+
+	gl.TexSubImage2D(
+		gl.TEXTURE_2D,
+		0,
+		int32(region.Min.X),
+		int32(region.Min.Y),
+		int32(region.Dx()),
+		int32(region.Dy()),
+		gl.RGBA,
+		gl.UNSIGNED_BYTE,
+		gl.Ptr(atlas.Pix[atlas.PixOffset(region.Min.X, region.Min.Y):]),
+	)
+}
+
+func (backend *glBackend) compileShader(
+	kind uint32,
+	source string,
+) (uint32, error) {
+	handle := gl.CreateShader(kind)
+
+	buffer, free := gl.Strs(source + "\x00")
+	defer free()
+
+	gl.ShaderSource(handle, 1, buffer, nil)
+	gl.CompileShader(handle)
+
+	var result int32
+	gl.GetShaderiv(handle, gl.COMPILE_STATUS, &result)
+	if result == gl.FALSE {
+		var length int32
+		gl.GetShaderiv(handle, gl.INFO_LOG_LENGTH, &length)
+
+		err := strings.Repeat("\x00", int(length+1))
+		gl.GetShaderInfoLog(handle, length, nil, gl.Str(err))
+
+		return 0, karma.Describe("source", source).Format(
+			err,
+			"{shader} compilation error",
+		)
+	}
+
+	return handle, nil
+}
+
+func (backend *glBackend) debug(
+	source uint32,
+	kind uint32,
+	id uint32,
+	severity uint32,
+	length int32,
+	message string,
+	userParam unsafe.Pointer,
+) {
+	logger := log.Warningf
+
+	switch kind {
+	case gl.DEBUG_TYPE_ERROR:
+		logger = log.Errorf
+	case gl.DEBUG_TYPE_OTHER:
+		logger = log.Tracef
+	}
+
+	logger(
+		"{gl} %s | type=0x%x severity=0x%x source=0x%x",
+		message,
+		kind,
+		severity,
+		source,
+	)
+}
+
+// Some X11 magic here.
+//
+// We need to set internal override redirect flag as window attribute so WM
+// will not manage our window, so it can be created without focus.
+//
+// Useful for making notification-like windows.
+func overrideRedirect(window *glfw.Window) {
+	var attrs C.XSetWindowAttributes
+	attrs.override_redirect = 1
+
+	C.XChangeWindowAttributes(
+		(*C.Display)(glfw.GetX11Display()),
+		(C.Window)(window.GetX11Window()),
+		C.CWOverrideRedirect,
+		&attrs,
+	)
+}
+
+func getAction(action glfw.Action) Action {
+	switch action {
+	case glfw.Release:
+		return ActionRelease
+	case glfw.Repeat:
+		return ActionRepeat
+	default:
+		return ActionPress
+	}
+}
+
+func getMods(mods glfw.ModifierKey) Mods {
+	var result Mods
+
+	if mods&glfw.ModShift != 0 {
+		result |= ModShift
+	}
+
+	if mods&glfw.ModControl != 0 {
+		result |= ModControl
+	}
+
+	if mods&glfw.ModAlt != 0 {
+		result |= ModAlt
+	}
+
+	if mods&glfw.ModSuper != 0 {
+		result |= ModSuper
+	}
+
+	return result
+}
+
+// getZoom returns zoom step of key: Ctrl+Plus and Ctrl+Minus zoom in and
+// out, while Ctrl+0 resets zoom. Keys on keypad are recognized too.
+func getZoom(key glfw.Key, mods glfw.ModifierKey) Zoom {
+	if mods&glfw.ModControl == 0 {
+		return ZoomNone
+	}
+
+	switch key {
+	case glfw.KeyEqual, glfw.KeyKPAdd:
+		return ZoomIn
+	case glfw.KeyMinus, glfw.KeyKPSubtract:
+		return ZoomOut
+	case glfw.Key0, glfw.KeyKP0:
+		return ZoomReset
+	default:
+		return ZoomNone
+	}
+}
+
+func MapKeyToSymbol(key glfw.Key, mods glfw.ModifierKey) string {
+	symbol := InputKeys[key]
+	if symbol == "" {
+		symbol = InputKeyUnknown
+	}
+
+	ignore := map[glfw.Key]bool{
+		glfw.KeyLeftControl:  true,
+		glfw.KeyLeftShift:    true,
+		glfw.KeyLeftAlt:      true,
+		glfw.KeyLeftSuper:    true,
+		glfw.KeyRightControl: true,
+		glfw.KeyRightShift:   true,
+		glfw.KeyRightAlt:     true,
+		glfw.KeyRightSuper:   true,
+	}
+
+	if ignore[key] {
+		return symbol
+	}
+
+	symbol = InputMods[mods&glfw.ModControl] + symbol
+	symbol = InputMods[mods&glfw.ModShift] + symbol
+	symbol = InputMods[mods&glfw.ModAlt] + symbol
+	symbol = InputMods[mods&glfw.ModSuper] + symbol
+
+	return symbol
+}
+
+var InputKeyUnknown = `Unknown`
+
+var InputMods = map[glfw.ModifierKey]string{
+	glfw.ModControl: `Ctrl+`,
+	glfw.ModShift:   `Shift+`,
+	glfw.ModAlt:     `Alt+`,
+	glfw.ModSuper:   `Super+`,
+}
+
+var InputKeys = map[glfw.Key]string{
+	glfw.KeySpace:        `Space`,
+	glfw.KeyApostrophe:   `'`,
+	glfw.KeyComma:        `,`,
+	glfw.KeyMinus:        `-`,
+	glfw.KeyPeriod:       `.`,
+	glfw.KeySlash:        `/`,
+	glfw.Key0:            `0`,
+	glfw.Key1:            `1`,
+	glfw.Key2:            `2`,
+	glfw.Key3:            `3`,
+	glfw.Key4:            `4`,
+	glfw.Key5:            `5`,
+	glfw.Key6:            `6`,
+	glfw.Key7:            `7`,
+	glfw.Key8:            `8`,
+	glfw.Key9:            `9`,
+	glfw.KeySemicolon:    `;`,
+	glfw.KeyEqual:        `=`,
+	glfw.KeyA:            `A`,
+	glfw.KeyB:            `B`,
+	glfw.KeyC:            `C`,
+	glfw.KeyD:            `D`,
+	glfw.KeyE:            `E`,
+	glfw.KeyF:            `F`,
+	glfw.KeyG:            `G`,
+	glfw.KeyH:            `H`,
+	glfw.KeyI:            `I`,
+	glfw.KeyJ:            `J`,
+	glfw.KeyK:            `K`,
+	glfw.KeyL:            `L`,
+	glfw.KeyM:            `M`,
+	glfw.KeyN:            `N`,
+	glfw.KeyO:            `O`,
+	glfw.KeyP:            `P`,
+	glfw.KeyQ:            `Q`,
+	glfw.KeyR:            `R`,
+	glfw.KeyS:            `S`,
+	glfw.KeyT:            `T`,
+	glfw.KeyU:            `U`,
+	glfw.KeyV:            `V`,
+	glfw.KeyW:            `W`,
+	glfw.KeyX:            `X`,
+	glfw.KeyY:            `Y`,
+	glfw.KeyZ:            `Z`,
+	glfw.KeyLeftBracket:  `[`,
+	glfw.KeyBackslash:    `\`,
+	glfw.KeyRightBracket: `]`,
+	glfw.KeyGraveAccent:  "`",
+	glfw.KeyEscape:       `Esc`,
+	glfw.KeyEnter:        `Enter`,
+	glfw.KeyTab:          `Tab`,
+	glfw.KeyBackspace:    `BackSpace`,
+	glfw.KeyInsert:       `Insert`,
+	glfw.KeyDelete:       `Delete`,
+	glfw.KeyRight:        `Right`,
+	glfw.KeyLeft:         `Left`,
+	glfw.KeyDown:         `Down`,
+	glfw.KeyUp:           `Up`,
+	glfw.KeyPageUp:       `PageUp`,
+	glfw.KeyPageDown:     `PageDown`,
+	glfw.KeyHome:         `Home`,
+	glfw.KeyEnd:          `End`,
+	glfw.KeyCapsLock:     `CapsLock`,
+	glfw.KeyScrollLock:   `ScrollLock`,
+	glfw.KeyNumLock:      `NumLock`,
+	glfw.KeyPrintScreen:  `PrintScreen`,
+	glfw.KeyPause:        `Pause`,
+	glfw.KeyF1:           `F1`,
+	glfw.KeyF2:           `F2`,
+	glfw.KeyF3:           `F3`,
+	glfw.KeyF4:           `F4`,
+	glfw.KeyF5:           `F5`,
+	glfw.KeyF6:           `F6`,
+	glfw.KeyF7:           `F7`,
+	glfw.KeyF8:           `F8`,
+	glfw.KeyF9:           `F9`,
+	glfw.KeyF10:          `F10`,
+	glfw.KeyF11:          `F11`,
+	glfw.KeyF12:          `F12`,
+	glfw.KeyF13:          `F13`,
+	glfw.KeyF14:          `F14`,
+	glfw.KeyF15:          `F15`,
+	glfw.KeyF16:          `F16`,
+	glfw.KeyF17:          `F17`,
+	glfw.KeyF18:          `F18`,
+	glfw.KeyF19:          `F19`,
+	glfw.KeyF20:          `F20`,
+	glfw.KeyF21:          `F21`,
+	glfw.KeyF22:          `F22`,
+	glfw.KeyF23:          `F23`,
+	glfw.KeyF24:          `F24`,
+	glfw.KeyF25:          `F25`,
+	glfw.KeyKP0:          `0`,
+	glfw.KeyKP1:          `1`,
+	glfw.KeyKP2:          `2`,
+	glfw.KeyKP3:          `3`,
+	glfw.KeyKP4:          `4`,
+	glfw.KeyKP5:          `5`,
+	glfw.KeyKP6:          `6`,
+	glfw.KeyKP7:          `7`,
+	glfw.KeyKP8:          `8`,
+	glfw.KeyKP9:          `9`,
+	glfw.KeyKPDecimal:    `.`,
+	glfw.KeyKPDivide:     `/`,
+	glfw.KeyKPMultiply:   `*`,
+	glfw.KeyKPSubtract:   `-`,
+	glfw.KeyKPAdd:        `+`,
+	glfw.KeyKPEnter:      `Enter`,
+	glfw.KeyKPEqual:      `=`,
+	glfw.KeyLeftShift:    `LShift`,
+	glfw.KeyLeftControl:  `LCtrl`,
+	glfw.KeyLeftAlt:      `LAlt`,
+	glfw.KeyLeftSuper:    `LSuper`,
+	glfw.KeyRightShift:   `RShift`,
+	glfw.KeyRightControl: `RCtrl`,
+	glfw.KeyRightAlt:     `RAlt`,
+	glfw.KeyRightSuper:   `RSuper`,
+	glfw.KeyMenu:         `Menu`,
+}
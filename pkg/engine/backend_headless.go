@@ -0,0 +1,165 @@
+package engine
+
+import (
+	"image"
+	"image/color"
+	"sync"
+
+	"github.com/seletskiy/mainframe/pkg/fonts"
+	"github.com/seletskiy/mainframe/pkg/protocol/messages"
+)
+
+// headlessBackend doesn't use GPU or display server. Windows exist only in
+// memory and their cells are composited in software into image of window
+// size, so engine can run in environments without display, like CI
+// containers.
+type headlessBackend struct {
+	wake chan struct{}
+}
+
+type headlessWindow struct {
+	sync.Mutex
+
+	width  int
+	height int
+
+	closed bool
+
+	// frame is image window is composited into.
+	frame *image.RGBA
+}
+
+// NewHeadlessBackend creates backend which composites windows in memory.
+func NewHeadlessBackend() Backend {
+	return &headlessBackend{
+		wake: make(chan struct{}, 1),
+	}
+}
+
+func (backend *headlessBackend) Init() error {
+	return nil
+}
+
+func (backend *headlessBackend) Terminate() {
+}
+
+func (backend *headlessBackend) CreateWindow(
+	width int,
+	height int,
+	options *messages.Open,
+	callbacks WindowCallbacks,
+) (Window, error) {
+	return &headlessWindow{
+		width:  width,
+		height: height,
+	}, nil
+}
+
+// GetMonitorSize returns zero size, since there is no monitor.
+func (backend *headlessBackend) GetMonitorSize() (int, int) {
+	return 0, 0
+}
+
+func (backend *headlessBackend) Wait() {
+	<-backend.wake
+}
+
+func (backend *headlessBackend) Wakeup() {
+	select {
+	case backend.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (backend *headlessBackend) FreeFont(font *fonts.Font) {
+}
+
+func (backend *headlessBackend) Free() {
+}
+
+// GetSize returns size of window in pixels. Headless window has no
+// content scale, so it's the same as framebuffer size.
+func (window *headlessWindow) GetSize() (int, int) {
+	window.Lock()
+	defer window.Unlock()
+
+	return window.width, window.height
+}
+
+func (window *headlessWindow) GetFramebufferSize() (int, int) {
+	return window.GetSize()
+}
+
+func (window *headlessWindow) GetScale() float64 {
+	return 1
+}
+
+func (window *headlessWindow) SetSize(width, height int) {
+	window.Lock()
+	defer window.Unlock()
+
+	window.width, window.height = width, height
+}
+
+// SetPos does nothing, since headless window has no position.
+func (window *headlessWindow) SetPos(x, y int) {
+}
+
+func (window *headlessWindow) Close() {
+	window.Lock()
+	defer window.Unlock()
+
+	window.closed = true
+}
+
+func (window *headlessWindow) ShouldClose() bool {
+	window.Lock()
+	defer window.Unlock()
+
+	return window.closed
+}
+
+func (window *headlessWindow) Destroy() {
+	window.Lock()
+	defer window.Unlock()
+
+	window.frame = nil
+}
+
+// Present composites screen into window frame.
+func (window *headlessWindow) Present(
+	screen *Screen,
+	foreground color.RGBA,
+	background color.RGBA,
+	palette Palette,
+) error {
+	window.Lock()
+	defer window.Unlock()
+
+	size := image.Pt(window.width, window.height)
+
+	if window.frame == nil || window.frame.Rect.Size() != size {
+		window.frame = image.NewRGBA(image.Rectangle{Max: size})
+	}
+
+	screen.composite(window.frame, foreground, background, palette)
+
+	return nil
+}
+
+// Capture returns copy of window frame.
+func (window *headlessWindow) Capture() (*image.RGBA, error) {
+	window.Lock()
+	defer window.Unlock()
+
+	if window.frame == nil {
+		size := image.Rect(0, 0, window.width, window.height)
+
+		return image.NewRGBA(size), nil
+	}
+
+	frame := *window.frame
+	frame.Pix = append([]uint8{}, window.frame.Pix...)
+
+	return &frame, nil
+}
@@ -0,0 +1,21 @@
+//go:build headless
+// +build headless
+
+package engine
+
+// noGLBackend replaces GL backend in builds with `headless` tag, which
+// don't depend on OpenGL, GLFW and X11 libraries. It fails to initialize,
+// so only headless backend can be used.
+type noGLBackend struct {
+	Backend
+}
+
+// NewGLBackend returns backend which reports that GL backend is not
+// available in this build.
+func NewGLBackend() Backend {
+	return noGLBackend{NewHeadlessBackend()}
+}
+
+func (noGLBackend) Init() error {
+	return ErrNoGLBackend
+}
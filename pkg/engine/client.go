@@ -2,26 +2,59 @@ package engine
 
 import (
 	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image/png"
+	"io"
 	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
 
 	"github.com/reconquest/karma-go"
+	"github.com/seletskiy/mainframe/pkg/log"
 	"github.com/seletskiy/mainframe/pkg/protocol/messages"
-	"github.com/seletskiy/mainframe/pkg/protocol/text"
 )
 
+type Reply interface {
+	messages.Serializable
+	messages.Identified
+}
+
 type Client struct {
 	Connection net.Conn
 	Context    *Context
 	Engine     *Engine
+
+	codec struct {
+		sync.Mutex
+
+		Codec
+	}
+
+	// transactions is a stack of shadow screens created by `begin` command,
+	// top one receives all output commands.
+	transactions []*Screen
 }
 
 func (client *Client) Serve() {
-	scanner := bufio.NewScanner(client.Connection)
+	reader := bufio.NewReader(client.Connection)
 
-	for scanner.Scan() {
-		line := scanner.Text()
+	for {
+		data, err := client.getCodec().Read(reader)
+		if err != nil {
+			if err != io.EOF {
+				log.Error(
+					karma.Format(err, "unable to read message").Error(),
+				)
+			}
+
+			break
+		}
 
-		message, err := text.Parse(line)
+		message, err := client.getCodec().Parse(data)
 		if err != nil {
 			err = client.Error(err)
 			if err != nil {
@@ -48,10 +81,39 @@ func (client *Client) Serve() {
 
 		case *messages.Clear:
 			err = client.handleClear(message)
+
+		case *messages.Copy:
+			err = client.handleCopy(message, false)
+
+		case *messages.Move:
+			err = client.handleCopy(&message.Copy, true)
+
+		case *messages.Capture:
+			err = client.handleCapture(message)
+
+		case *messages.Protocol:
+			err = client.handleProtocol(message)
+
+		case *messages.Begin:
+			err = client.handleBegin(message)
+
+		case *messages.Commit:
+			err = client.handleCommit(message)
+
+		case *messages.Abort:
+			err = client.handleAbort(message)
+
+		case *messages.Set:
+			err = client.handleSet(message)
+
+		case *messages.EventKeyboard, *messages.EventInput:
+			err = client.handleEvent(message)
 		}
 
 		if err != nil {
-			err = client.Error(err)
+			err = client.Reply(message, &messages.Error{
+				Message: err.Error(),
+			})
 			if err != nil {
 				// FIXME
 				panic(err)
@@ -59,13 +121,49 @@ func (client *Client) Serve() {
 		}
 	}
 
+	// Uncommitted transactions are discarded.
+	client.transactions = nil
+
 	if client.Context != nil {
 		client.Context.Close()
 	}
 }
 
 func (client *Client) Send(message messages.Serializable) error {
-	_, err := client.Connection.Write([]byte(text.Serialize(message)))
+	client.codec.Lock()
+	defer client.codec.Unlock()
+
+	return client.send(message)
+}
+
+// Reply sends reply to given request. If request had ID, then reply will
+// have same ID.
+func (client *Client) Reply(request messages.Tagged, reply Reply) error {
+	if request, ok := request.(messages.Identified); ok {
+		reply.SetID(request.GetID())
+	}
+
+	return client.Send(reply)
+}
+
+func (client *Client) Error(err error) error {
+	// Errors returned by parser already have request ID set if it was
+	// possible to find ID in the request.
+	if reply, ok := err.(*messages.Error); ok {
+		return client.Send(reply)
+	}
+
+	return client.Send(&messages.Error{
+		Message: err.Error(),
+	})
+}
+
+func (client *Client) send(message messages.Serializable) error {
+	if client.codec.Codec == nil {
+		client.codec.Codec = Codecs[messages.ProtocolText]
+	}
+
+	_, err := client.Connection.Write(client.codec.Serialize(message))
 	if err != nil {
 		return karma.
 			Describe("message", message.Serialize()).
@@ -78,20 +176,65 @@ func (client *Client) Send(message messages.Serializable) error {
 	return nil
 }
 
-func (client *Client) Error(err error) error {
-	return client.Send(&messages.Error{
-		Message: err.Error(),
-	})
+func (client *Client) getCodec() Codec {
+	client.codec.Lock()
+	defer client.codec.Unlock()
+
+	if client.codec.Codec == nil {
+		client.codec.Codec = Codecs[messages.ProtocolText]
+	}
+
+	return client.codec.Codec
 }
 
 func (client *Client) handlePut(message *messages.Put) error {
+	screen, err := client.getScreen()
+	if err != nil {
+		return err
+	}
+
 	var reply messages.OK
 
-	if !client.Context.Screen.Put(message) {
+	if message.Tick != nil {
+		var (
+			onscreen = true
+			replaced = 0
+		)
+
+		status, err := client.schedule(
+			*message.Tick,
+			func(screen *Screen) {
+				onscreen, replaced = screen.put(message)
+			},
+		)
+		if err != nil {
+			return err
+		}
+
+		reply.Set(status, true)
+
+		if status == ScheduleApplied {
+			setPutReply(&reply, onscreen, replaced)
+		}
+
+		return client.Reply(message, &reply)
+	}
+
+	onscreen, replaced := screen.Put(message)
+
+	setPutReply(&reply, onscreen, replaced)
+
+	return client.Reply(message, &reply)
+}
+
+func setPutReply(reply *messages.OK, onscreen bool, replaced int) {
+	if !onscreen {
 		reply.Set("offscreen", true)
 	}
 
-	return client.Send(&reply)
+	if replaced > 0 {
+		reply.Set("replaced", replaced)
+	}
 }
 
 func (client *Client) handleSubscribe(message *messages.Subscribe) error {
@@ -109,7 +252,7 @@ func (client *Client) handleSubscribe(message *messages.Subscribe) error {
 		client.Context.Subscribe(client, SubscriptionInput)
 	}
 
-	return client.Send(&reply)
+	return client.Reply(message, &reply)
 }
 
 func (client *Client) handleOpen(message *messages.Open) error {
@@ -123,9 +266,9 @@ func (client *Client) handleOpen(message *messages.Open) error {
 	var reply messages.OK
 
 	// TODO: reply with id of newly created window
-	//reply.Set("id", 123)
+	//reply.Set("window", 123)
 
-	return client.Send(&reply)
+	return client.Reply(message, &reply)
 }
 
 func (client *Client) handleGet(message *messages.Get) error {
@@ -133,19 +276,183 @@ func (client *Client) handleGet(message *messages.Get) error {
 
 	switch {
 	case message.Font.Set:
-		font := client.Engine.GetFont()
+		// Client without window receives information about default font.
+		var (
+			font   = client.Engine.GetFont()
+			scaled = font
+			scale  = 1.0
+		)
+
+		if client.Context != nil {
+			font = client.Context.GetFont()
+			scaled = client.Context.Screen.GetFont()
+			scale = client.Context.GetScale()
+		}
+
+		reply.Set("font", font.GetPath())
+		reply.Set("font_size", int(font.GetSize()))
+		reply.Set("font_dpi", int(font.GetDPI()))
+		reply.Set("line_height", font.GetLineHeight())
+		reply.Set("letter_spacing", font.GetLetterSpacing())
+		reply.Set("scale", int(scale*100))
+
+		reply.Set("width", scaled.GetWidth())
+		reply.Set("height", scaled.GetHeight())
+
+		stats := scaled.GetAtlasStats()
+
+		reply.Set("glyphs", stats.Glyphs)
+		reply.Set("atlas_width", stats.Width)
+		reply.Set("atlas_height", stats.Height)
+		reply.Set("atlas_cells", stats.Cells)
+		reply.Set("atlas_used", stats.Used)
+
+	case message.Cells.Set:
+		err := client.getCells(message, &reply)
+		if err != nil {
+			return err
+		}
+	}
+
+	return client.Reply(message, &reply)
+}
+
+// getCells reads back contents of screen region. Text of cells is
+// reported row by row, separated by newlines. Colors and styles are
+// reported in same layout, one space-separated value per cell, `-`
+// stands for default color or plain style.
+func (client *Client) getCells(message *messages.Get, reply *messages.OK) error {
+	screen, err := client.getScreen()
+	if err != nil {
+		return err
+	}
+
+	x, y, rows, columns := screen.GetRegion(
+		message.Cells.X,
+		message.Cells.Y,
+		message.Cells.Rows,
+		message.Cells.Columns,
+	)
+
+	cells, origin, onscreen := screen.Read(x, y, rows, columns)
+
+	var (
+		text   = make([]string, len(cells))
+		fg     = make([]string, len(cells))
+		bg     = make([]string, len(cells))
+		styles = make([]string, len(cells))
+	)
+
+	for i, row := range cells {
+		var (
+			rowFg     = make([]string, len(row))
+			rowBg     = make([]string, len(row))
+			rowStyles = make([]string, len(row))
+		)
+
+		for j, cell := range row {
+			text[i] += cell.Char
+
+			rowFg[j] = formatCellColor(cell.Foreground)
+			rowBg[j] = formatCellColor(cell.Background)
+			rowStyles[j] = formatCellStyle(cell.Style)
+		}
+
+		fg[i] = strings.Join(rowFg, " ")
+		bg[i] = strings.Join(rowBg, " ")
+		styles[i] = strings.Join(rowStyles, " ")
+	}
+
+	reply.Set("x", origin.X)
+	reply.Set("y", origin.Y)
+
+	if len(cells) > 0 {
+		reply.Set("columns", len(cells[0]))
+	} else {
+		reply.Set("columns", 0)
+	}
+
+	reply.Set("rows", len(cells))
+
+	reply.Set("text", strings.Join(text, "\n"))
+	reply.Set("fg", strings.Join(fg, "\n"))
+	reply.Set("bg", strings.Join(bg, "\n"))
+	reply.Set("attrs", strings.Join(styles, "\n"))
+
+	if !onscreen {
+		reply.Set("offscreen", true)
+	}
+
+	return nil
+}
+
+// formatCellColor formats color of cell either as palette index or in
+// same `#rrggbb[aa]` form which is used in commands.
+func formatCellColor(color *messages.Color) string {
+	switch {
+	case color == nil:
+		return "-"
+
+	case color.Indexed:
+		return strconv.Itoa(color.Index)
+
+	case color.RGBA.A == 0xff:
+		return fmt.Sprintf(
+			"#%02x%02x%02x",
+			color.RGBA.R,
+			color.RGBA.G,
+			color.RGBA.B,
+		)
+
+	default:
+		return fmt.Sprintf(
+			"#%02x%02x%02x%02x",
+			color.RGBA.R,
+			color.RGBA.G,
+			color.RGBA.B,
+			color.RGBA.A,
+		)
+	}
+}
+
+// formatCellStyle formats style attributes of cell as comma-separated list
+// of style flags of `put` command.
+func formatCellStyle(style int32) string {
+	flags := []struct {
+		attr int32
+		name string
+	}{
+		{AttrBold, "bold"},
+		{AttrItalic, "italic"},
+		{AttrUnderline, "underline"},
+		{AttrStrike, "strike"},
+		{AttrReverse, "reverse"},
+		{AttrDim, "dim"},
+	}
+
+	names := []string{}
+
+	for _, flag := range flags {
+		if style&flag.attr != 0 {
+			names = append(names, flag.name)
+		}
+	}
 
-		reply.Set("width", font.GetWidth())
-		reply.Set("height", font.GetHeight())
+	if len(names) == 0 {
+		return "-"
 	}
 
-	return client.Send(&reply)
+	return strings.Join(names, ",")
 }
 
 func (client *Client) handleReshape(message *messages.Reshape) error {
+	if client.Context == nil {
+		return ErrNoWindow
+	}
+
 	var reply messages.OK
 
-	font := client.Engine.GetFont()
+	font := client.Context.Screen.GetFont()
 
 	var (
 		width  int
@@ -160,27 +467,71 @@ func (client *Client) handleReshape(message *messages.Reshape) error {
 		height = *message.Height
 	}
 
-	if message.Columns != nil {
-		width = *message.Columns * font.GetWidth()
+	// Grid is measured in framebuffer pixels, which differ from window
+	// coordinates on HiDPI monitors.
+	var (
+		windowWidth, windowHeight           = client.Context.Window.GetSize()
+		framebufferWidth, framebufferHeight = client.Context.Window.GetFramebufferSize()
+	)
+
+	if message.Columns != nil && framebufferWidth > 0 {
+		width = *message.Columns * font.GetWidth() *
+			windowWidth / framebufferWidth
 	}
 
-	if message.Rows != nil {
-		height = *message.Rows * font.GetHeight()
+	if message.Rows != nil && framebufferHeight > 0 {
+		height = *message.Rows * font.GetHeight() *
+			windowHeight / framebufferHeight
 	}
 
 	if message.X != nil && message.Y != nil {
-		client.Context.Window.SetPos(*message.X, *message.Y)
+		x, y := *message.X, *message.Y
+
+		// Negative position is relative to right and bottom edges of
+		// monitor, so `-1` will align window to the edge.
+		if x < 0 || y < 0 {
+			monitorWidth, monitorHeight := client.Engine.GetMonitorSize()
+
+			windowWidth, windowHeight := client.Context.Window.GetSize()
+			if width > 0 && height > 0 {
+				windowWidth, windowHeight = width, height
+			}
+
+			if x < 0 {
+				x += monitorWidth - windowWidth + 1
+			}
+
+			if y < 0 {
+				y += monitorHeight - windowHeight + 1
+			}
+		}
+
+		client.Context.Window.SetPos(x, y)
 	}
 
 	if width > 0 && height > 0 {
 		client.Context.Window.SetSize(width, height)
-		client.Context.Resize(width, height)
+
+		if windowWidth > 0 && windowHeight > 0 {
+			client.Context.Resize(
+				width*framebufferWidth/windowWidth,
+				height*framebufferHeight/windowHeight,
+			)
+		}
+
+		// Not every backend refreshes window after resize.
+		client.Context.Screen.Render()
 	}
 
-	return client.Send(&reply)
+	return client.Reply(message, &reply)
 }
 
 func (client *Client) handleClear(message *messages.Clear) error {
+	screen, err := client.getScreen()
+	if err != nil {
+		return err
+	}
+
 	var reply messages.OK
 
 	var (
@@ -199,19 +550,363 @@ func (client *Client) handleClear(message *messages.Clear) error {
 		y = *message.Y
 	}
 
+	columns, rows = screen.GetGrid()
+
 	if message.Rows != nil {
 		rows = *message.Rows
-	} else {
-		rows = client.Context.Screen.rows
 	}
 
 	if message.Columns != nil {
 		columns = *message.Columns
+	}
+
+	if message.Tick != nil {
+		status, err := client.schedule(
+			*message.Tick,
+			func(screen *Screen) {
+				screen.clear(x, y, rows, columns)
+			},
+		)
+		if err != nil {
+			return err
+		}
+
+		reply.Set(status, true)
+
+		return client.Reply(message, &reply)
+	}
+
+	screen.Clear(x, y, rows, columns)
+
+	return client.Reply(message, &reply)
+}
+
+// handleCopy copies region of screen; region is moved if clear is true.
+func (client *Client) handleCopy(message *messages.Copy, clear bool) error {
+	screen, err := client.getScreen()
+	if err != nil {
+		return err
+	}
+
+	var reply messages.OK
+
+	x, y, rows, columns := screen.GetRegion(
+		message.X,
+		message.Y,
+		message.Rows,
+		message.Columns,
+	)
+
+	if message.Tick != nil {
+		onscreen := true
+
+		status, err := client.schedule(
+			*message.Tick,
+			func(screen *Screen) {
+				onscreen = screen.copy(
+					x,
+					y,
+					rows,
+					columns,
+					message.ToX,
+					message.ToY,
+					clear,
+				)
+			},
+		)
+		if err != nil {
+			return err
+		}
+
+		reply.Set(status, true)
+
+		if status == ScheduleApplied && !onscreen {
+			reply.Set("offscreen", true)
+		}
+
+		return client.Reply(message, &reply)
+	}
+
+	onscreen := screen.Copy(
+		x,
+		y,
+		rows,
+		columns,
+		message.ToX,
+		message.ToY,
+		clear,
+	)
+	if !onscreen {
+		reply.Set("offscreen", true)
+	}
+
+	return client.Reply(message, &reply)
+}
+
+// handleEvent emits event sent by client as if it was produced by window,
+// so synthetic input can be fed into headless windows. Tick of event is
+// replaced with tick of window.
+func (client *Client) handleEvent(message messages.Tagged) error {
+	if client.Context == nil {
+		return ErrNoWindow
+	}
+
+	tick := client.Context.GetTick()
+
+	switch event := message.(type) {
+	case *messages.EventKeyboard:
+		event.Tick = tick
+
+		client.Context.Emit(SubscriptionKeyboard, event)
+
+	case *messages.EventInput:
+		event.Tick = tick
+
+		client.Context.Emit(SubscriptionInput, event)
+	}
+
+	var reply messages.OK
+
+	return client.Reply(message, &reply)
+}
+
+// handleCapture replies with PNG image of window region, or writes image
+// into specified file. Window is captured even in transaction, since image
+// should contain what is displayed.
+func (client *Client) handleCapture(message *messages.Capture) error {
+	if client.Context == nil {
+		return ErrNoWindow
+	}
+
+	screen := client.Context.Screen
+
+	frame, err := client.Engine.Capture(client.Context)
+	if err != nil {
+		return err
+	}
+
+	var reply messages.OK
+
+	bounds := frame.Bounds()
+
+	// Whole window is captured by default, including area which is not
+	// covered by grid.
+	if message.X != nil || message.Rows != nil || message.Columns != nil {
+		region, onscreen := screen.GetBounds(
+			message.X,
+			message.Y,
+			message.Rows,
+			message.Columns,
+		)
+		if !onscreen {
+			reply.Set("offscreen", true)
+		}
+
+		bounds = bounds.Intersect(region)
+	}
+
+	if bounds.Empty() {
+		return fmt.Errorf("region is empty or offscreen")
+	}
+
+	var buffer bytes.Buffer
+
+	err = png.Encode(&buffer, frame.SubImage(bounds))
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to encode image",
+		)
+	}
+
+	reply.Set("width", bounds.Dx())
+	reply.Set("height", bounds.Dy())
+
+	if message.Path != nil {
+		err = writeImage(*message.Path, buffer.Bytes(), message.Overwrite)
+		if err != nil {
+			return karma.Format(
+				err,
+				"unable to write image: %s",
+				*message.Path,
+			)
+		}
+
+		reply.Set("path", *message.Path)
 	} else {
-		_, columns = client.Context.Screen.GetGrid()
+		reply.Set("data", base64.StdEncoding.EncodeToString(buffer.Bytes()))
+	}
+
+	return client.Reply(message, &reply)
+}
+
+// writeImage writes image into file at given path. Existing file is
+// replaced only if overwrite is true, so clients can't accidentally
+// replace files writable by mainframe process.
+func writeImage(path string, data []byte, overwrite bool) error {
+	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if overwrite {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+
+	file, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		return err
 	}
 
-	client.Context.Screen.Clear(x, y, rows, columns)
+	_, err = file.Write(data)
+	if err != nil {
+		file.Close()
+
+		return err
+	}
+
+	return file.Close()
+}
+
+func (client *Client) handleProtocol(message *messages.Protocol) error {
+	client.codec.Lock()
+	defer client.codec.Unlock()
+
+	// Reply is sent using current protocol, so client knows that every
+	// following message will be in requested form.
+	err := client.send(&messages.OK{
+		Identity: messages.Identity{ID: message.ID},
+	})
+	if err != nil {
+		return err
+	}
+
+	client.codec.Codec = Codecs[message.Kind]
+
+	return nil
+}
+
+func (client *Client) handleBegin(message *messages.Begin) error {
+	screen, err := client.getScreen()
+	if err != nil {
+		return err
+	}
+
+	client.transactions = append(client.transactions, screen.Begin())
+
+	var reply messages.OK
+
+	return client.Reply(message, &reply)
+}
+
+func (client *Client) handleCommit(message *messages.Commit) error {
+	if len(client.transactions) == 0 {
+		return ErrNoTransaction
+	}
+
+	var (
+		last   = len(client.transactions) - 1
+		shadow = client.transactions[last]
+	)
+
+	client.transactions = client.transactions[:last]
+
+	// Nested transaction is committed into outer transaction and only
+	// outermost transaction is committed into window screen.
+	screen, err := client.getScreen()
+	if err != nil {
+		return err
+	}
+
+	screen.Commit(shadow)
+
+	var reply messages.OK
+
+	return client.Reply(message, &reply)
+}
+
+func (client *Client) handleAbort(message *messages.Abort) error {
+	if len(client.transactions) == 0 {
+		return ErrNoTransaction
+	}
+
+	client.transactions = client.transactions[:len(client.transactions)-1]
+
+	var reply messages.OK
+
+	return client.Reply(message, &reply)
+}
+
+func (client *Client) handleSet(message *messages.Set) error {
+	if client.Context == nil {
+		return ErrNoWindow
+	}
+
+	if message.Foreground != nil {
+		client.Context.SetForeground(*message.Foreground)
+	}
+
+	if message.Background != nil {
+		client.Context.SetBackground(*message.Background)
+	}
+
+	if message.Palette != nil {
+		client.Context.SetPaletteColor(*message.Palette, *message.Color)
+	}
+
+	var (
+		font = client.Context.GetFont()
+		key  = getFontKey(font).derive(
+			message.Font,
+			message.FontSize,
+			message.FontDPI,
+			message.LineHeight,
+			message.LetterSpacing,
+		)
+	)
+
+	if key != getFontKey(font) {
+		font, err := client.Engine.loadFont(font, key)
+		if err != nil {
+			return karma.Format(err, "unable to load font")
+		}
+
+		defer client.Engine.releaseFont(font)
+
+		err = client.Engine.SetWindowFont(client.Context, font)
+		if err != nil {
+			return karma.Format(err, "unable to scale font")
+		}
+	}
+
+	var reply messages.OK
+
+	return client.Reply(message, &reply)
+}
+
+// getScreen returns screen which should receive output commands: either
+// shadow screen of current transaction or screen of bound window.
+func (client *Client) getScreen() (*Screen, error) {
+	if len(client.transactions) > 0 {
+		return client.transactions[len(client.transactions)-1], nil
+	}
+
+	if client.Context == nil {
+		return nil, ErrNoWindow
+	}
+
+	return client.Context.Screen, nil
+}
+
+// schedule queues command on window tick. Scheduled commands can't be used
+// in transactions, because they are applied to window screen directly.
+func (client *Client) schedule(
+	tick int,
+	apply func(*Screen),
+) (string, error) {
+	if len(client.transactions) > 0 {
+		return "", ErrTickInTransaction
+	}
+
+	if client.Context == nil {
+		return "", ErrNoWindow
+	}
 
-	return client.Send(&reply)
+	return client.Context.Schedule(int64(tick), apply), nil
 }
@@ -0,0 +1,79 @@
+package engine
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+
+	"github.com/seletskiy/mainframe/pkg/protocol/binary"
+	"github.com/seletskiy/mainframe/pkg/protocol/messages"
+	"github.com/seletskiy/mainframe/pkg/protocol/text"
+)
+
+// Codec reads, decodes and encodes messages of single protocol form.
+//
+// Connection always starts in text form and can be switched to other form
+// using `protocol` command.
+type Codec interface {
+	Read(reader *bufio.Reader) ([]byte, error)
+	Parse(data []byte) (messages.Tagged, error)
+	Serialize(message messages.Serializable) []byte
+}
+
+var Codecs = map[string]Codec{
+	messages.ProtocolText:   TextCodec{},
+	messages.ProtocolBinary: BinaryCodec{},
+}
+
+type TextCodec struct{}
+
+// Read reads single line. Line is limited by the same size as binary frame,
+// so misbehaving client will not be able to exhaust memory.
+func (TextCodec) Read(reader *bufio.Reader) ([]byte, error) {
+	var line []byte
+
+	for {
+		chunk, err := reader.ReadSlice('\n')
+
+		if len(line)+len(chunk) > binary.MaxFrameSize {
+			return nil, fmt.Errorf(
+				"line is too long (max %d bytes)",
+				binary.MaxFrameSize,
+			)
+		}
+
+		line = append(line, chunk...)
+
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+
+		if err != nil && len(line) == 0 {
+			return nil, err
+		}
+
+		return bytes.TrimRight(line, "\r\n"), nil
+	}
+}
+
+func (TextCodec) Parse(data []byte) (messages.Tagged, error) {
+	return text.Parse(string(data))
+}
+
+func (TextCodec) Serialize(message messages.Serializable) []byte {
+	return text.Serialize(message)
+}
+
+type BinaryCodec struct{}
+
+func (BinaryCodec) Read(reader *bufio.Reader) ([]byte, error) {
+	return binary.Read(reader)
+}
+
+func (BinaryCodec) Parse(data []byte) (messages.Tagged, error) {
+	return binary.Parse(data)
+}
+
+func (BinaryCodec) Serialize(message messages.Serializable) []byte {
+	return binary.Serialize(message)
+}
@@ -1,9 +1,10 @@
 package engine
 
 import (
+	"image/color"
 	"sync"
 
-	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/seletskiy/mainframe/pkg/fonts"
 	"github.com/seletskiy/mainframe/pkg/protocol/messages"
 )
 
@@ -14,22 +15,65 @@ const (
 )
 
 type Context struct {
-	Window *glfw.Window
+	Window Window
 	Screen *Screen
 
-	vao  uint32
+	// Transparent is set for windows with transparent framebuffer, so
+	// cells without background will show desktop below window.
+	Transparent bool
+
+	colors struct {
+		sync.Mutex
+
+		foreground color.RGBA
+		background color.RGBA
+		palette    Palette
+	}
+
 	tick int64
 
+	// fontSize is initial font size of window, which is restored when zoom
+	// is reset.
+	fontSize float64
+
+	// zoom holds font size window is being zoomed to, while font of that
+	// size is loaded in background.
+	zoom struct {
+		sync.Mutex
+
+		size float64
+	}
+
+	// font holds font requested for window and content scale of monitor
+	// window is displayed on. Screen font is rasterized at DPI multiplied
+	// by scale.
+	font struct {
+		sync.Mutex
+
+		base  *fonts.Font
+		scale float64
+	}
+
 	subscriptions struct {
 		sync.Mutex
 
 		clients map[int][]*Client
 	}
+
+	schedule struct {
+		sync.Mutex
+
+		commands []ScheduledCommand
+	}
 }
 
 func NewContext() *Context {
 	context := &Context{}
 	context.subscriptions.clients = map[int][]*Client{}
+	context.colors.foreground = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	context.colors.background = color.RGBA{0x00, 0x00, 0x00, 0xff}
+	context.colors.palette = DefaultPalette
+	context.font.scale = 1
 	return context
 }
 
@@ -45,12 +89,54 @@ func (context *Context) Subscribe(client *Client, subscription int) {
 func (context *Context) Resize(width, height int) {
 	rows, columns := context.Screen.Resize(width, height)
 
+	context.notifyResize(width, height, columns, rows)
+}
+
+// SetFont changes font of window to given font, which is displayed as
+// scaled font. Since grid size depends on font size, subscribers are
+// notified about new amount of rows and columns.
+func (context *Context) SetFont(font *fonts.Font, scaled *fonts.Font) {
+	context.font.Lock()
+	context.font.base = font
+	context.font.Unlock()
+
+	rows, columns := context.Screen.SetFont(scaled)
+
+	width, height := context.Screen.GetSize()
+
+	context.notifyResize(width, height, columns, rows)
+}
+
+// GetFont returns font requested for window, which is not scaled.
+func (context *Context) GetFont() *fonts.Font {
+	context.font.Lock()
+	defer context.font.Unlock()
+
+	return context.font.base
+}
+
+// GetScale returns content scale of monitor window is displayed on.
+func (context *Context) GetScale() float64 {
+	context.font.Lock()
+	defer context.font.Unlock()
+
+	return context.font.scale
+}
+
+func (context *Context) setScale(scale float64) {
+	context.font.Lock()
+	defer context.font.Unlock()
+
+	context.font.scale = scale
+}
+
+func (context *Context) notifyResize(width, height, columns, rows int) {
 	// TODO move out of render loop
-	subscribers := context.subscriptions.clients[SubscriptionResize]
-	for _, client := range subscribers {
-		client.Send(&messages.EventResize{
+	context.Emit(
+		SubscriptionResize,
+		&messages.EventResize{
 			Event: messages.Event{
-				Tick: context.tick,
+				Tick: context.GetTick(),
 				Kind: "resize",
 			},
 
@@ -58,65 +144,106 @@ func (context *Context) Resize(width, height int) {
 			Height:  height,
 			Columns: columns,
 			Rows:    rows,
-		})
-	}
+
+			Scale: int(context.GetScale() * 100),
+		},
+	)
 }
 
-func (context *Context) Input(
-	char rune,
-	mods glfw.ModifierKey,
-) {
-	subscribers := context.subscriptions.clients[SubscriptionInput]
-	for _, client := range subscribers {
-		client.Send(&messages.EventInput{
+func (context *Context) Input(char rune, mods Mods) {
+	context.Emit(
+		SubscriptionInput,
+		&messages.EventInput{
 			Event: messages.Event{
-				Tick: context.tick,
+				Tick: context.GetTick(),
 				Kind: "input",
 			},
 
 			Char: char,
 
-			Shift: 0 != mods&glfw.ModShift,
-			Ctrl:  0 != mods&glfw.ModControl,
-			Alt:   0 != mods&glfw.ModAlt,
-			Super: 0 != mods&glfw.ModSuper,
-		})
-	}
+			Shift: 0 != mods&ModShift,
+			Ctrl:  0 != mods&ModControl,
+			Alt:   0 != mods&ModAlt,
+			Super: 0 != mods&ModSuper,
+		},
+	)
 }
 
-func (context *Context) Key(
-	action glfw.Action,
-	mods glfw.ModifierKey,
-	key glfw.Key,
-	scancode int,
-) {
-	subscribers := context.subscriptions.clients[SubscriptionKeyboard]
-
-	for _, client := range subscribers {
-		client.Send(&messages.EventKeyboard{
+func (context *Context) Key(key Key) {
+	context.Emit(
+		SubscriptionKeyboard,
+		&messages.EventKeyboard{
 			Event: messages.Event{
-				Tick: context.tick,
+				Tick: context.GetTick(),
 				Kind: "keyboard",
 			},
 
-			Press:   action == glfw.Press,
-			Release: action == glfw.Release,
-			Repeat:  action == glfw.Repeat,
+			Press:   key.Action == ActionPress,
+			Release: key.Action == ActionRelease,
+			Repeat:  key.Action == ActionRepeat,
+
+			Symbol: key.Symbol,
+			Code:   key.Code,
 
-			Symbol: MapKeyToSymbol(key, mods),
-			Code:   scancode,
+			Shift: 0 != key.Mods&ModShift,
+			Ctrl:  0 != key.Mods&ModControl,
+			Alt:   0 != key.Mods&ModAlt,
+			Super: 0 != key.Mods&ModSuper,
+		},
+	)
+}
 
-			Shift: 0 != mods&glfw.ModShift,
-			Ctrl:  0 != mods&glfw.ModControl,
-			Alt:   0 != mods&glfw.ModAlt,
-			Super: 0 != mods&glfw.ModSuper,
-		})
+// Emit sends event to all clients which are subscribed to given
+// subscription.
+func (context *Context) Emit(subscription int, event messages.Serializable) {
+	context.subscriptions.Lock()
+	subscribers := context.subscriptions.clients[subscription]
+	context.subscriptions.Unlock()
+
+	for _, client := range subscribers {
+		client.Send(event)
 	}
 }
 
+// SetForeground sets color which is used for cells without foreground.
+func (context *Context) SetForeground(fg color.RGBA) {
+	context.colors.Lock()
+	context.colors.foreground = fg
+	context.colors.Unlock()
+
+	context.Screen.Render()
+}
+
+// SetBackground sets color which is used for cells without background.
+func (context *Context) SetBackground(bg color.RGBA) {
+	context.colors.Lock()
+	context.colors.background = bg
+	context.colors.Unlock()
+
+	context.Screen.Render()
+}
+
+// SetPaletteColor changes palette entry, so all cells that refer to that
+// entry will be recolored on next render.
+func (context *Context) SetPaletteColor(index int, value color.RGBA) {
+	context.colors.Lock()
+	context.colors.palette[index] = value
+	context.colors.Unlock()
+
+	context.Screen.Render()
+}
+
+// GetColors returns default foreground, background and palette colors.
+func (context *Context) GetColors() (color.RGBA, color.RGBA, Palette) {
+	context.colors.Lock()
+	defer context.colors.Unlock()
+
+	return context.colors.foreground,
+		context.colors.background,
+		context.colors.palette
+}
+
 func (context *Context) Close() {
-	if context.Window != nil {
-		context.Window.SetShouldClose(true)
-		context.Screen.Render()
-	}
+	context.Window.Close()
+	context.Screen.Render()
 }
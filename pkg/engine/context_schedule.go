@@ -0,0 +1,108 @@
+package engine
+
+import (
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	ScheduleApplied = "applied"
+	ScheduleQueued  = "queued"
+	ScheduleDropped = "dropped"
+)
+
+type ScheduledCommand struct {
+	Tick  int64
+	Apply func(*Screen)
+}
+
+// Schedule queues command to be applied to screen on frame with given tick.
+//
+// Command is dropped if given tick is older than tick of last rendered
+// frame. If tick is not in the future, command is applied immediately.
+// Otherwise command is queued and will be applied right before rendering
+// first frame which tick is greater or equal to given tick.
+func (context *Context) Schedule(tick int64, apply func(*Screen)) string {
+	context.schedule.Lock()
+	defer context.schedule.Unlock()
+
+	if tick < context.GetTick() {
+		return ScheduleDropped
+	}
+
+	context.schedule.commands = append(
+		context.schedule.commands,
+		ScheduledCommand{tick, apply},
+	)
+
+	// Commands with same tick are applied in order they were received.
+	sort.SliceStable(
+		context.schedule.commands,
+		func(i, j int) bool {
+			return context.schedule.commands[i].Tick <
+				context.schedule.commands[j].Tick
+		},
+	)
+
+	now := getTick()
+
+	if tick <= now {
+		if context.flush(now) {
+			context.Screen.Render()
+		}
+
+		return ScheduleApplied
+	}
+
+	time.AfterFunc(
+		time.Duration(tick-now)*time.Microsecond,
+		context.Screen.Render,
+	)
+
+	return ScheduleQueued
+}
+
+func (context *Context) GetTick() int64 {
+	return atomic.LoadInt64(&context.tick)
+}
+
+// Flush applies all queued commands which tick is less or equal to given
+// tick.
+func (context *Context) Flush(tick int64) bool {
+	context.schedule.Lock()
+	defer context.schedule.Unlock()
+
+	return context.flush(tick)
+}
+
+func (context *Context) flush(tick int64) bool {
+	var due int
+
+	for due < len(context.schedule.commands) {
+		if context.schedule.commands[due].Tick > tick {
+			break
+		}
+
+		due++
+	}
+
+	if due == 0 {
+		return false
+	}
+
+	context.Screen.Lock()
+	defer context.Screen.Unlock()
+
+	for _, command := range context.schedule.commands[:due] {
+		command.Apply(context.Screen)
+	}
+
+	context.schedule.commands = context.schedule.commands[due:]
+
+	return true
+}
+
+func getTick() int64 {
+	return time.Now().UnixNano() / int64(time.Microsecond)
+}
@@ -1,14 +1,11 @@
 package engine
 
 import (
-	"runtime"
-	"strings"
+	"image"
+	"image/color"
 	"sync"
-	"time"
-	"unsafe"
+	"sync/atomic"
 
-	"github.com/go-gl/gl/v4.1-core/gl"
-	"github.com/go-gl/glfw/v3.2/glfw"
 	"github.com/reconquest/karma-go"
 	"github.com/seletskiy/mainframe/pkg/fonts"
 	"github.com/seletskiy/mainframe/pkg/log"
@@ -18,6 +15,8 @@ import (
 const (
 	DefaultWindowWidth  = 640
 	DefaultWindowHeight = 480
+
+	DelegatesQueueSize = 16
 )
 
 type Delegate struct {
@@ -26,22 +25,7 @@ type Delegate struct {
 }
 
 type Engine struct {
-	vertices struct {
-		points []float32
-
-		buffers struct {
-			triangles  uint32
-			glyphs     uint32
-			attributes uint32
-			colors     uint32
-		}
-	}
-
-	shaders struct {
-		program  uint32
-		vertex   uint32
-		fragment uint32
-	}
+	backend Backend
 
 	contexts map[*Screen]*Context
 
@@ -51,8 +35,22 @@ type Engine struct {
 	}
 
 	font struct {
-		handle  *fonts.Font
-		texture uint32
+		sync.Mutex
+
+		// handle is default font for new windows.
+		handle *fonts.Font
+
+		// cache holds fonts loaded for windows, so windows with identical
+		// font configuration share same font atlas.
+		cache map[fontKey]*fonts.Font
+
+		// pinned counts fonts which are loaded, but not yet attached to
+		// window, so they are not freed in between.
+		pinned map[*fonts.Font]int
+
+		// saving tracks fonts which are saved into cache in background
+		// after they are forgotten.
+		saving sync.WaitGroup
 	}
 
 	delegates chan Delegate
@@ -60,35 +58,26 @@ type Engine struct {
 	running bool
 }
 
-func New() *Engine {
+// New creates engine which displays windows using given backend, like
+// NewGLBackend.
+func New(backend Backend) *Engine {
 	engine := &Engine{
-		delegates: make(chan Delegate, 0),
+		backend:   backend,
+		delegates: make(chan Delegate, DelegatesQueueSize),
 	}
 
 	engine.contexts = map[*Screen]*Context{}
 	engine.queue.screens = map[*Screen]bool{}
+	engine.font.cache = map[fontKey]*fonts.Font{}
+	engine.font.pinned = map[*fonts.Font]int{}
 
 	return engine
 }
 
 func (engine *Engine) Init() error {
-	// Required for OpenGL to work.
-	runtime.LockOSThread()
-
-	err := gl.Init()
+	err := engine.backend.Init()
 	if err != nil {
-		return karma.Format(
-			err,
-			"{gl} unable to init",
-		)
-	}
-
-	err = glfw.Init()
-	if err != nil {
-		return karma.Format(
-			err,
-			"{glfw} unable to init",
-		)
+		return err
 	}
 
 	engine.running = true
@@ -115,26 +104,47 @@ func (engine *Engine) CreateWindow(options *messages.Open) (*Context, error) {
 		height = int(*options.Height)
 	}
 
+	font := engine.GetFont()
+
+	key := getFontKey(font).derive(
+		options.Font,
+		options.FontSize,
+		options.FontDPI,
+		options.LineHeight,
+		options.LetterSpacing,
+	)
+
+	if key != getFontKey(font) {
+		font, err = engine.loadFont(font, key)
+		if err != nil {
+			return nil, karma.Format(
+				err,
+				"unable to load window font",
+			)
+		}
+
+		defer engine.releaseFont(font)
+	}
+
 	if options.Columns != nil {
-		width = *options.Columns * engine.font.handle.GetWidth()
+		width = *options.Columns * font.GetWidth()
 	}
 
 	if options.Rows != nil {
-		height = *options.Rows * engine.font.handle.GetHeight()
+		height = *options.Rows * font.GetHeight()
 	}
 
-	// All GL commands should be evaluated in same system thread, so we
+	// All backend calls should be evaluated in same system thread, so we
 	// need to send them to main engine thread to execute.
 	engine.delegate(
 		func() {
-			// TODO return error
-			context = engine.createWindow(width, height, options)
+			context, err = engine.createWindow(width, height, font, options)
 		},
 	)
 	if err != nil {
 		return nil, karma.Format(
 			err,
-			"{glfw} unable to create window",
+			"unable to create window",
 		)
 	}
 
@@ -146,7 +156,7 @@ func (engine *Engine) Render(screen *Screen) {
 	engine.queue.screens[screen] = true
 	engine.queue.Unlock()
 
-	glfw.PostEmptyEvent()
+	engine.backend.Wakeup()
 }
 
 func (engine *Engine) Running() bool {
@@ -168,33 +178,94 @@ func (engine *Engine) Loop() error {
 		}
 		engine.queue.Unlock()
 
+		engine.freeFonts()
+
 		select {
 		case delegate := <-engine.delegates:
 			delegate.callback()
 			delegate.barrier <- struct{}{}
 		default:
-			glfw.WaitEvents()
+			engine.backend.Wait()
 		}
 	}
 
 	if len(engine.contexts) == 0 {
-		engine.free()
+		engine.backend.Free()
+		engine.saveFonts()
 	}
 
 	return nil
 }
 
+// SetFont sets default font for new windows.
 func (engine *Engine) SetFont(font *fonts.Font) {
+	engine.font.Lock()
+	defer engine.font.Unlock()
+
 	engine.font.handle = font
+	engine.font.cache[getFontKey(font)] = font
 }
 
+// GetFont returns default font for new windows.
 func (engine *Engine) GetFont() *fonts.Font {
+	engine.font.Lock()
+	defer engine.font.Unlock()
+
 	return engine.font.handle
 }
 
+// GetMonitorSize returns size of primary monitor in pixels.
+func (engine *Engine) GetMonitorSize() (int, int) {
+	var width, height int
+
+	engine.delegate(func() {
+		width, height = engine.backend.GetMonitorSize()
+	})
+
+	return width, height
+}
+
+// Capture renders window and returns image of its contents as it's
+// displayed, so commands scheduled up to now are visible on image.
+func (engine *Engine) Capture(context *Context) (*image.RGBA, error) {
+	var (
+		frame *image.RGBA
+		err   error
+	)
+
+	engine.delegate(func() {
+		err = engine.render(context.Screen)
+		if err != nil {
+			return
+		}
+
+		// Window is destroyed on render if it was closed.
+		if _, ok := engine.contexts[context.Screen]; !ok {
+			err = ErrNoWindow
+			return
+		}
+
+		frame, err = context.Window.Capture()
+	})
+	if err != nil {
+		return nil, karma.Format(
+			err,
+			"unable to capture window",
+		)
+	}
+
+	return frame, nil
+}
+
 func (engine *Engine) Stop() {
 	engine.delegate(func() {
-		glfw.Terminate()
+		// Fonts which are saved in background are waited first, so same
+		// cache file is never written twice at once.
+		engine.font.saving.Wait()
+		engine.saveFonts()
+
+		engine.backend.Terminate()
+
 		engine.running = false
 	})
 }
@@ -202,149 +273,123 @@ func (engine *Engine) Stop() {
 func (engine *Engine) createWindow(
 	width,
 	height int,
+	font *fonts.Font,
 	options *messages.Open,
-) *Context {
-	glfw.WindowHint(glfw.ContextVersionMajor, 4)
-	glfw.WindowHint(glfw.ContextVersionMinor, 1)
-	glfw.WindowHint(glfw.OpenGLProfile, glfw.OpenGLCoreProfile)
-	glfw.WindowHint(glfw.OpenGLForwardCompatible, glfw.True)
-	glfw.WindowHint(glfw.DoubleBuffer, glfw.False)
-
-	var position bool
+) (*Context, error) {
+	context := NewContext()
+	context.Transparent = options.Transparent
+	context.fontSize = font.GetSize()
 
-	if options.X != nil && options.Y != nil {
-		position = true
+	window, err := engine.backend.CreateWindow(
+		width,
+		height,
+		options,
+		WindowCallbacks{
+			Key: func(key Key) {
+				if options.Zoom && key.Zoom != ZoomNone {
+					engine.zoom(context, key)
+					return
+				}
+
+				context.Key(key)
+			},
+
+			Input: context.Input,
+
+			Rescale: func(scale float64) {
+				engine.rescale(context, scale)
+			},
+
+			Refresh: func() {
+				err := engine.render(context.Screen)
+				if err != nil {
+					panic(err)
+				}
+			},
+		},
+	)
+	if err != nil {
+		return nil, err
 	}
 
-	// FIXME: hidden has no use now, because there is no way to show hidden
-	// window from mainframe API.
-	if options.Hidden || options.Raw || position {
-		glfw.WindowHint(glfw.Visible, glfw.False)
-	} else {
-		glfw.WindowHint(glfw.Visible, glfw.True)
-	}
+	context.Window = window
 
-	// FIXME: not really works with i3 for the reason it doesn't check for
-	// NET_WM_STATE_ABOVE when setting floating mode:
-	//
-	// https://github.com/i3/i3/blob/next/src/manage.c#L439
-	//if options.Floating {
-	//    glfw.WindowHint(glfw.Floating, glfw.True)
-	//} else {
-	//    glfw.WindowHint(glfw.Floating, glfw.False)
-	//}
-
-	if options.Fixed {
-		glfw.WindowHint(glfw.Resizable, glfw.False)
-	} else {
-		glfw.WindowHint(glfw.Resizable, glfw.True)
-	}
+	context.font.base = font
+	context.font.scale = window.GetScale()
+
+	scaled, err := engine.scaleFont(font, context.font.scale)
+	if err != nil {
+		log.Error(karma.Format(err, "unable to scale window font").Error())
 
-	if options.Bare {
-		glfw.WindowHint(glfw.Decorated, glfw.False)
+		scaled = font
 	} else {
-		glfw.WindowHint(glfw.Decorated, glfw.True)
+		defer engine.releaseFont(scaled)
 	}
 
-	var parent *glfw.Window
-	for _, context := range engine.contexts {
-		parent = context.Window
-		break
+	// Window size for given columns and rows is calculated using unscaled
+	// font, so window is resized to fit requested grid on HiDPI monitors.
+	if options.Columns != nil || options.Rows != nil {
+		var (
+			windowWidth, windowHeight           = window.GetSize()
+			framebufferWidth, framebufferHeight = window.GetFramebufferSize()
+		)
+
+		if options.Columns != nil && framebufferWidth > 0 {
+			width = *options.Columns * scaled.GetWidth() *
+				windowWidth / framebufferWidth
+		}
+
+		if options.Rows != nil && framebufferHeight > 0 {
+			height = *options.Rows * scaled.GetHeight() *
+				windowHeight / framebufferHeight
+		}
+
+		window.SetSize(width, height)
 	}
 
-	var window *glfw.Window
-	window, err := glfw.CreateWindow(
-		width,
-		height,
-		options.Title,
-		nil,
-		parent,
-	)
-	if err != nil {
-		return nil
+	width, height = window.GetFramebufferSize()
+
+	if options.Transparent {
+		context.colors.background = color.RGBA{0, 0, 0, 0}
 	}
 
-	if position {
-		window.SetPos(*options.X, *options.Y)
+	if options.Foreground != nil {
+		context.colors.foreground = *options.Foreground
 	}
 
-	if options.Raw {
-		overrideRedirect(window)
+	if options.Background != nil {
+		context.colors.background = *options.Background
 	}
 
-	context := NewContext()
-	context.Window = window
 	context.Screen = NewScreen(
 		width,
 		height,
-		engine.font.handle,
+		scaled,
 		engine.Render,
 	)
 
-	window.SetCharModsCallback(
-		func(
-			_ *glfw.Window,
-			char rune,
-			mods glfw.ModifierKey,
-		) {
-			context.Input(char, mods)
-		},
-	)
-
-	window.SetKeyCallback(
-		func(
-			_ *glfw.Window,
-			key glfw.Key,
-			scancode int,
-			action glfw.Action,
-			mods glfw.ModifierKey,
-		) {
-			context.Key(action, mods, key, scancode)
-		},
-	)
-
-	window.SetRefreshCallback(
-		func(
-			_ *glfw.Window,
-		) {
-			err := engine.render(context.Screen)
-			if err != nil {
-				panic(err)
-			}
-		},
-	)
-
-	//window.SetCloseCallback(
-	//    func(
-	//        _ *glfw.Window,
-	//    ) {
-	//        fmt.Fprintln(os.Stderr, "XXXXXX engine.go:308  CLOSE")
-	//    },
-	//)
-
-	window.MakeContextCurrent()
-
-	glfw.SwapInterval(0)
-
-	engine.clear()
-
-	gl.Enable(gl.DEBUG_OUTPUT)
-	gl.DebugMessageCallback(engine.debug, nil)
-	gl.GenVertexArrays(1, &context.vao)
-
 	engine.contexts[context.Screen] = context
 
-	if !options.Hidden && (options.Raw || position) {
-		window.Show()
+	// Window is presented right away, so it's never displayed with
+	// garbage contents.
+	err = engine.render(context.Screen)
+	if err != nil {
+		return nil, err
 	}
 
-	return context
+	return context, nil
 }
 
 func (engine *Engine) render(screen *Screen) error {
-	context := engine.contexts[screen]
+	context, ok := engine.contexts[screen]
+	if !ok {
+		// Window can be already destroyed when scheduled render happens.
+		return nil
+	}
 
-	context.tick = time.Now().UnixNano() / int64(time.Microsecond)
+	tick := getTick()
+
+	atomic.StoreInt64(&context.tick, tick)
 
 	if context.Window.ShouldClose() {
 		context.Window.Destroy()
@@ -353,7 +398,9 @@ func (engine *Engine) render(screen *Screen) error {
 	}
 
 	var (
-		windowWidth, windowHeight = context.Window.GetSize()
+		// Framebuffer size differs from window size on HiDPI monitors,
+		// so screen grid is calculated in framebuffer pixels.
+		windowWidth, windowHeight = context.Window.GetFramebufferSize()
 		screenWidth, screenHeight = context.Screen.GetSize()
 	)
 
@@ -361,229 +408,15 @@ func (engine *Engine) render(screen *Screen) error {
 		context.Resize(windowWidth, windowHeight)
 	}
 
+	// Commands scheduled on this tick should be visible on this frame.
+	context.Flush(tick)
+
 	screen.Lock()
 	defer screen.Unlock()
 
-	context.Window.MakeContextCurrent()
-
-	err := engine.initShaders()
-	if err != nil {
-		return err
-	}
-
-	err = engine.initVertices()
-	if err != nil {
-		return err
-	}
-
-	engine.initTextures()
-
-	gl.BindVertexArray(context.vao)
-
-	gl.Enable(gl.BLEND)
-	gl.BlendFunc(gl.SRC_ALPHA, gl.ONE_MINUS_SRC_ALPHA)
-
-	engine.clear()
-
-	var (
-		glyphWidth  = engine.font.handle.GetWidth()
-		glyphHeight = engine.font.handle.GetHeight()
-	)
-
-	gl.Viewport(0, 0, int32(windowWidth), int32(windowHeight))
-
-	gl.Uniform2i(0, int32(windowWidth), int32(windowHeight))
-	gl.Uniform2i(1, int32(glyphWidth), int32(glyphHeight))
-
-	gl.BindBuffer(gl.ARRAY_BUFFER, engine.vertices.buffers.triangles)
-	gl.VertexAttribPointer(0, 2, gl.FLOAT, false, 2*4, gl.PtrOffset(0))
-	gl.EnableVertexAttribArray(0)
-
-	gl.BindBuffer(gl.ARRAY_BUFFER, engine.vertices.buffers.glyphs)
-	gl.BufferData(
-		gl.ARRAY_BUFFER,
-		4*len(context.Screen.GetCells()),
-		gl.Ptr(context.Screen.GetCells()),
-		gl.DYNAMIC_DRAW,
-	)
-	gl.VertexAttribIPointer(1, 2, gl.INT, 2*4, gl.PtrOffset(0))
-	gl.EnableVertexAttribArray(1)
-	gl.VertexAttribDivisor(1, 1)
-
-	gl.BindBuffer(gl.ARRAY_BUFFER, engine.vertices.buffers.attributes)
-	gl.BufferData(
-		gl.ARRAY_BUFFER,
-		4*len(context.Screen.GetAttrs()),
-		gl.Ptr(context.Screen.GetAttrs()),
-		gl.DYNAMIC_DRAW,
-	)
-	gl.VertexAttribIPointer(2, 1, gl.INT, 1*4, gl.PtrOffset(0))
-	gl.EnableVertexAttribArray(2)
-	gl.VertexAttribDivisor(2, 1)
-
-	gl.BindBuffer(gl.ARRAY_BUFFER, engine.vertices.buffers.colors)
-	gl.BufferData(
-		gl.ARRAY_BUFFER,
-		4*len(context.Screen.GetColors()),
-		gl.Ptr(context.Screen.GetColors()),
-		gl.DYNAMIC_DRAW,
-	)
-	gl.VertexAttribIPointer(3, 2, gl.INT, 2*4, gl.PtrOffset(0))
-	gl.EnableVertexAttribArray(3)
-	gl.VertexAttribDivisor(3, 1)
-
-	gl.DrawArraysInstanced(
-		gl.TRIANGLE_STRIP,
-		0,
-		6,
-		int32(context.Screen.GetArea()),
-	)
-
-	gl.Finish()
-
-	return nil
-}
-
-func (engine *Engine) clear() {
-	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
-	gl.ClearColor(0, 0, 0, 1)
-}
-
-func (engine *Engine) initTextures() error {
-	if engine.font.texture > 0 {
-		gl.BindTexture(gl.TEXTURE_2D, engine.font.texture)
-
-		return nil
-	}
-
-	gl.GenTextures(1, &engine.font.texture)
-	gl.BindTexture(gl.TEXTURE_2D, engine.font.texture)
-
-	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_BORDER)
-	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_R, gl.CLAMP_TO_BORDER)
-	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.NEAREST)
-	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.NEAREST)
-
-	gl.TexImage2D(
-		gl.TEXTURE_2D,
-		0,
-		gl.RGBA,
-		int32(engine.font.handle.Image.Bounds().Size().X),
-		int32(engine.font.handle.Image.Bounds().Size().Y),
-		0,
-		gl.RGBA,
-		gl.UNSIGNED_BYTE,
-		gl.Ptr(engine.font.handle.Image.Pix),
-	)
-
-	return nil
-}
-
-func (engine *Engine) initShaders() error {
-	if engine.shaders.program > 0 {
-		gl.UseProgram(engine.shaders.program)
-
-		return nil
-	}
-
-	var err error
+	foreground, background, palette := context.GetColors()
 
-	engine.shaders.vertex, err = engine.compileShader(
-		gl.VERTEX_SHADER,
-		vertexShader,
-	)
-	if err != nil {
-		return karma.Format(
-			err,
-			"{gl} unable to compile vertex shader",
-		)
-	}
-
-	engine.shaders.fragment, err = engine.compileShader(
-		gl.FRAGMENT_SHADER,
-		fragmentShader,
-	)
-	if err != nil {
-		return karma.Format(
-			err,
-			"{gl} unable to compile fragment shader",
-		)
-	}
-
-	engine.shaders.program = gl.CreateProgram()
-
-	gl.AttachShader(engine.shaders.program, engine.shaders.vertex)
-	gl.AttachShader(engine.shaders.program, engine.shaders.fragment)
-	gl.LinkProgram(engine.shaders.program)
-	gl.UseProgram(engine.shaders.program)
-
-	return nil
-}
-
-func (engine *Engine) initVertices() error {
-	if engine.vertices.points != nil {
-		return nil
-	}
-
-	//         ->
-	// (0; 1) X--X (1; 1)
-	//      ^ |\ | |
-	//      | | \| V
-	// (0; 0) X--X (1; 0)
-	//         <-
-	engine.vertices.points = []float32{
-		0, 0,
-		0, 1,
-		1, 0,
-
-		1, 0,
-		0, 1,
-		1, 1,
-	}
-
-	// First buffer for vertices, which form triangles, which form cells.
-	gl.GenBuffers(1, &engine.vertices.buffers.triangles)
-
-	gl.BindBuffer(gl.ARRAY_BUFFER, engine.vertices.buffers.triangles)
-
-	gl.BufferData(
-		gl.ARRAY_BUFFER,
-		len(engine.vertices.points)*4,
-		gl.Ptr(engine.vertices.points),
-		gl.STATIC_DRAW,
-	)
-
-	// Second buffer for vertices data, in this case it's glyph coordinates
-	// in font.
-	gl.GenBuffers(1, &engine.vertices.buffers.glyphs)
-
-	// Third buffer for vertices data, in this case it's cell attributes.
-	gl.GenBuffers(1, &engine.vertices.buffers.attributes)
-
-	// Fourth buffer for background/foreground colors.
-	gl.GenBuffers(1, &engine.vertices.buffers.colors)
-
-	return nil
-}
-
-func (engine *Engine) free() {
-	gl.DeleteBuffers(1, &engine.vertices.buffers.triangles)
-	gl.DeleteBuffers(1, &engine.vertices.buffers.glyphs)
-	gl.DeleteBuffers(1, &engine.vertices.buffers.attributes)
-	gl.DeleteBuffers(1, &engine.vertices.buffers.colors)
-	gl.DeleteTextures(1, &engine.font.texture)
-	gl.DeleteShader(engine.shaders.vertex)
-	gl.DeleteShader(engine.shaders.fragment)
-	gl.DeleteProgram(engine.shaders.program)
-
-	engine.vertices.points = nil
-	engine.vertices.buffers.triangles = 0
-	engine.vertices.buffers.glyphs = 0
-	engine.vertices.buffers.attributes = 0
-	engine.font.texture = 0
-	engine.shaders.vertex = 0
-	engine.shaders.fragment = 0
-	engine.shaders.program = 0
+	return context.Window.Present(screen, foreground, background, palette)
 }
 
 func (engine *Engine) delegate(callback func()) {
@@ -594,62 +427,11 @@ func (engine *Engine) delegate(callback func()) {
 		callback,
 	}
 
-	<-barrier
-}
-
-func (engine *Engine) compileShader(
-	kind uint32,
-	source string,
-) (uint32, error) {
-	handle := gl.CreateShader(kind)
-
-	buffer, free := gl.Strs(source + "\x00")
-	defer free()
-
-	gl.ShaderSource(handle, 1, buffer, nil)
-	gl.CompileShader(handle)
-
-	var result int32
-	gl.GetShaderiv(handle, gl.COMPILE_STATUS, &result)
-	if result == gl.FALSE {
-		var length int32
-		gl.GetShaderiv(handle, gl.INFO_LOG_LENGTH, &length)
-
-		err := strings.Repeat("\x00", int(length+1))
-		gl.GetShaderInfoLog(handle, length, nil, gl.Str(err))
-
-		return 0, karma.Describe("source", source).Format(
-			err,
-			"{shader} compilation error",
-		)
-	}
-
-	return handle, nil
-}
-
-func (engine *Engine) debug(
-	source uint32,
-	kind uint32,
-	id uint32,
-	severity uint32,
-	length int32,
-	message string,
-	userParam unsafe.Pointer,
-) {
-	logger := log.Warningf
-
-	switch kind {
-	case gl.DEBUG_TYPE_ERROR:
-		logger = log.Errorf
-	case gl.DEBUG_TYPE_OTHER:
-		logger = log.Tracef
+	// Engine loop can wait for window events, so it needs to be woken up
+	// to process delegate.
+	if engine.running {
+		engine.backend.Wakeup()
 	}
 
-	logger(
-		"{gl} %s | type=0x%x severity=0x%x source=0x%x",
-		message,
-		kind,
-		severity,
-		source,
-	)
+	<-barrier
 }
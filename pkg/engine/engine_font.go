@@ -0,0 +1,317 @@
+package engine
+
+import (
+	"math"
+
+	"github.com/reconquest/karma-go"
+	"github.com/seletskiy/mainframe/pkg/fonts"
+	"github.com/seletskiy/mainframe/pkg/log"
+)
+
+const (
+	// ZoomStep is amount of points font size is changed by on every zoom.
+	ZoomStep = 1
+
+	// ZoomMinSize is minimal font size which can be reached by zooming.
+	ZoomMinSize = 4
+)
+
+// fontKey identifies font configuration. All fonts are derived from
+// default font, so other options are the same.
+type fontKey struct {
+	path string
+	size float64
+	dpi  float64
+
+	lineHeight    int
+	letterSpacing int
+}
+
+func getFontKey(font *fonts.Font) fontKey {
+	return fontKey{
+		path: font.GetPath(),
+		size: font.GetSize(),
+		dpi:  font.GetDPI(),
+
+		lineHeight:    font.GetLineHeight(),
+		letterSpacing: font.GetLetterSpacing(),
+	}
+}
+
+// derive returns key with specified values changed; nil values keep ones
+// of given key.
+func (key fontKey) derive(
+	path *string,
+	size *int,
+	dpi *int,
+	lineHeight *int,
+	letterSpacing *int,
+) fontKey {
+	if path != nil {
+		key.path = *path
+	}
+
+	if size != nil {
+		key.size = float64(*size)
+	}
+
+	if dpi != nil {
+		key.dpi = float64(*dpi)
+	}
+
+	if lineHeight != nil {
+		key.lineHeight = *lineHeight
+	}
+
+	if letterSpacing != nil {
+		key.letterSpacing = *letterSpacing
+	}
+
+	return key
+}
+
+// loadFont returns font derived from given font with configuration
+// specified by key. Fonts are cached, so windows with identical font
+// configuration share same font atlas.
+//
+// Returned font is pinned, so it's not freed until it's attached to window,
+// and should be released via releaseFont afterwards.
+func (engine *Engine) loadFont(
+	base *fonts.Font,
+	key fontKey,
+) (*fonts.Font, error) {
+	engine.font.Lock()
+	font, ok := engine.font.cache[key]
+	if ok {
+		engine.font.pinned[font]++
+	}
+	engine.font.Unlock()
+
+	if ok {
+		return font, nil
+	}
+
+	// Font is loaded without lock, since it reads and parses font files,
+	// while cache is used on every iteration of engine loop.
+	font, err := base.Derive(
+		key.path,
+		key.size,
+		key.dpi,
+		fonts.FontLineHeight(key.lineHeight),
+		fonts.FontLetterSpacing(key.letterSpacing),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	engine.font.Lock()
+	defer engine.font.Unlock()
+
+	// Same font can be loaded concurrently, first one is shared.
+	if cached, ok := engine.font.cache[key]; ok {
+		font = cached
+	}
+
+	engine.font.cache[key] = font
+	engine.font.pinned[font]++
+
+	return font, nil
+}
+
+// releaseFont unpins font returned by loadFont or scaleFont, so it's freed
+// once no window uses it.
+func (engine *Engine) releaseFont(font *fonts.Font) {
+	engine.font.Lock()
+	defer engine.font.Unlock()
+
+	engine.font.pinned[font]--
+
+	if engine.font.pinned[font] <= 0 {
+		delete(engine.font.pinned, font)
+	}
+}
+
+// SetWindowFont changes font of window. Font is rasterized at DPI scaled
+// by content scale of monitor window is displayed on.
+func (engine *Engine) SetWindowFont(context *Context, font *fonts.Font) error {
+	scaled, err := engine.scaleFont(font, context.GetScale())
+	if err != nil {
+		return err
+	}
+
+	defer engine.releaseFont(scaled)
+
+	context.SetFont(font, scaled)
+
+	return nil
+}
+
+// scaleFont returns font rasterized for given content scale. Like loadFont,
+// it returns pinned font.
+func (engine *Engine) scaleFont(
+	font *fonts.Font,
+	scale float64,
+) (*fonts.Font, error) {
+	if scale == 1 {
+		engine.font.Lock()
+		engine.font.pinned[font]++
+		engine.font.Unlock()
+
+		return font, nil
+	}
+
+	key := getFontKey(font)
+
+	// Letter spacing is specified in pixels, so it is scaled along with
+	// glyphs.
+	key.dpi *= scale
+	key.letterSpacing = int(math.Round(float64(key.letterSpacing) * scale))
+
+	return engine.loadFont(font, key)
+}
+
+// rescale re-rasterizes window font when window is moved to monitor with
+// different content scale.
+func (engine *Engine) rescale(context *Context, scale float64) {
+	if scale == context.GetScale() {
+		return
+	}
+
+	context.setScale(scale)
+
+	err := engine.SetWindowFont(context, context.GetFont())
+	if err != nil {
+		log.Error(karma.Format(err, "unable to rescale window font").Error())
+	}
+}
+
+// freeFonts forgets fonts which are not used by any window, except default
+// font and fonts which are pinned until they are attached to window, and
+// releases resources allocated for them by backend. Forgotten fonts are
+// saved into cache in background, so render is not delayed by writing
+// atlases to disk.
+func (engine *Engine) freeFonts() {
+	used := map[*fonts.Font]bool{}
+
+	for _, context := range engine.contexts {
+		used[context.Screen.GetFont()] = true
+		used[context.GetFont()] = true
+	}
+
+	engine.font.Lock()
+	defer engine.font.Unlock()
+
+	used[engine.font.handle] = true
+
+	for key, font := range engine.font.cache {
+		if !used[font] && engine.font.pinned[font] == 0 {
+			delete(engine.font.cache, key)
+
+			engine.backend.FreeFont(font)
+
+			engine.font.saving.Add(1)
+
+			go func(font *fonts.Font) {
+				defer engine.font.saving.Done()
+
+				saveFont(font)
+			}(font)
+		}
+	}
+}
+
+// saveFonts writes atlases of all loaded fonts into cache, so glyphs
+// rasterized so far are available on next start.
+func (engine *Engine) saveFonts() {
+	engine.font.Lock()
+	defer engine.font.Unlock()
+
+	for _, font := range engine.font.cache {
+		saveFont(font)
+	}
+}
+
+func saveFont(font *fonts.Font) {
+	err := font.SaveCache()
+	if err != nil {
+		log.Error(karma.Format(err, "unable to save font cache").Error())
+	}
+}
+
+// zoom changes font size of window by zoom step of given key, like
+// Ctrl+Plus, or resets it to initial size on Ctrl+0.
+//
+// Zoom is called from window event callback, so font is loaded in
+// background to not freeze windows while font files are read.
+func (engine *Engine) zoom(context *Context, key Key) {
+	if key.Action == ActionRelease {
+		return
+	}
+
+	context.zoom.Lock()
+	defer context.zoom.Unlock()
+
+	var (
+		font    = context.GetFont()
+		current = font.GetSize()
+		size    float64
+	)
+
+	// Keys can be pressed faster than font is loaded, so zoom steps are
+	// counted from size which is being loaded.
+	if context.zoom.size != 0 {
+		current = context.zoom.size
+	}
+
+	switch key.Zoom {
+	case ZoomIn:
+		size = current + ZoomStep
+	case ZoomOut:
+		size = current - ZoomStep
+	case ZoomReset:
+		size = context.fontSize
+	}
+
+	if size < ZoomMinSize || size == current {
+		return
+	}
+
+	context.zoom.size = size
+
+	config := getFontKey(font)
+
+	config.size = size
+
+	go func() {
+		font, err := engine.loadFont(font, config)
+		if err == nil {
+			defer engine.releaseFont(font)
+		}
+
+		var scaled *fonts.Font
+		if err == nil {
+			scaled, err = engine.scaleFont(font, context.GetScale())
+		}
+
+		if err == nil {
+			defer engine.releaseFont(scaled)
+		}
+
+		context.zoom.Lock()
+		defer context.zoom.Unlock()
+
+		// Font which is loaded after next zoom step is discarded.
+		if context.zoom.size != size {
+			return
+		}
+
+		context.zoom.size = 0
+
+		if err != nil {
+			log.Error(karma.Format(err, "unable to zoom font").Error())
+			return
+		}
+
+		context.SetFont(font, scaled)
+	}()
+}
@@ -0,0 +1,18 @@
+package engine
+
+import (
+	"errors"
+)
+
+var (
+	ErrNoWindow      = errors.New("no window is bound to connection")
+	ErrNoTransaction = errors.New("no transaction in progress")
+
+	ErrNoGLBackend = errors.New(
+		"mainframe is built without GL backend, only headless mode is available",
+	)
+
+	ErrTickInTransaction = errors.New(
+		"tick can't be specified for commands in transaction",
+	)
+)
@@ -0,0 +1,64 @@
+package engine
+
+import (
+	"image/color"
+
+	"github.com/seletskiy/mainframe/pkg/protocol/messages"
+)
+
+type Palette [messages.PaletteSize]color.RGBA
+
+// DefaultPalette is the same 256-color palette that is used by xterm:
+// - 16 base colors;
+// - 6x6x6 color cube;
+// - 24 shades of gray.
+var DefaultPalette = func() Palette {
+	var palette Palette
+
+	base := []uint32{
+		0x000000, 0xcd0000, 0x00cd00, 0xcdcd00,
+		0x0000ee, 0xcd00cd, 0x00cdcd, 0xe5e5e5,
+		0x7f7f7f, 0xff0000, 0x00ff00, 0xffff00,
+		0x5c5cff, 0xff00ff, 0x00ffff, 0xffffff,
+	}
+
+	for i, value := range base {
+		palette[i] = color.RGBA{
+			uint8(value >> 16),
+			uint8(value >> 8),
+			uint8(value),
+			0xff,
+		}
+	}
+
+	levels := []uint8{0x00, 0x5f, 0x87, 0xaf, 0xd7, 0xff}
+
+	for i := 0; i < 216; i++ {
+		palette[16+i] = color.RGBA{
+			levels[i/36],
+			levels[i/6%6],
+			levels[i%6],
+			0xff,
+		}
+	}
+
+	for i := 0; i < 24; i++ {
+		level := uint8(8 + i*10)
+
+		palette[232+i] = color.RGBA{level, level, level, 0xff}
+	}
+
+	return palette
+}()
+
+// Pack converts palette into form suitable for passing into shader, where
+// every color is packed as 0xRRGGBBAA.
+func (palette *Palette) Pack() []uint32 {
+	packed := make([]uint32, len(palette))
+
+	for i := range palette {
+		packed[i] = uint32(packColor(&palette[i]))
+	}
+
+	return packed
+}
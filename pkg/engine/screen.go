@@ -1,10 +1,12 @@
 package engine
 
 import (
+	"image"
 	"image/color"
 	"strconv"
 	"sync"
 
+	"github.com/rivo/uniseg"
 	"github.com/seletskiy/mainframe/pkg/fonts"
 	"github.com/seletskiy/mainframe/pkg/protocol/messages"
 )
@@ -14,6 +16,32 @@ const (
 	AttrGlyph      = 1
 	AttrForeground = 2
 	AttrBackground = 4
+
+	// AttrForegroundIndexed and AttrBackgroundIndexed specify that
+	// corresponding color is an index in window palette.
+	AttrForegroundIndexed = 8
+	AttrBackgroundIndexed = 16
+
+	// Style attributes. Bold and italic select font face for glyph, while
+	// other styles are rendered by shader.
+	AttrBold      = 32
+	AttrItalic    = 64
+	AttrUnderline = 128
+	AttrStrike    = 256
+	AttrReverse   = 512
+	AttrDim       = 1024
+
+	// AttrWide marks left cell of double-width char and AttrWideTail marks
+	// right one. Tail cell refers to right half of glyph in font atlas.
+	AttrWide     = 2048
+	AttrWideTail = 4096
+
+	AttrStyle = AttrBold |
+		AttrItalic |
+		AttrUnderline |
+		AttrStrike |
+		AttrReverse |
+		AttrDim
 )
 
 type Screen struct {
@@ -32,6 +60,13 @@ type Screen struct {
 
 	regions map[string]ScreenRegion
 
+	// changes are tracked only for screens created by Begin, so they can
+	// be merged back into parent screen on commit.
+	changes struct {
+		cells   map[int]bool
+		regions map[string]bool
+	}
+
 	render func(*Screen)
 }
 
@@ -67,7 +102,7 @@ func NewScreen(
 	return screen
 }
 
-func (screen *Screen) SetForeground(x, y int, fg *color.RGBA) bool {
+func (screen *Screen) SetForeground(x, y int, fg *messages.Color) bool {
 	screen.Lock()
 	defer screen.Unlock()
 	defer screen.Render()
@@ -75,7 +110,7 @@ func (screen *Screen) SetForeground(x, y int, fg *color.RGBA) bool {
 	return screen.setForeground(x, y, fg)
 }
 
-func (screen *Screen) SetBackground(x int, y int, bg *color.RGBA) bool {
+func (screen *Screen) SetBackground(x int, y int, bg *messages.Color) bool {
 	screen.Lock()
 	defer screen.Unlock()
 	defer screen.Render()
@@ -83,24 +118,135 @@ func (screen *Screen) SetBackground(x int, y int, bg *color.RGBA) bool {
 	return screen.setBackground(x, y, bg)
 }
 
-func (screen *Screen) Put(message *messages.Put) bool {
+// Put changes cells according to given message. It returns false if some
+// cells are offscreen and amount of chars which are drawn using replacement
+// glyph, because they are missing in font.
+func (screen *Screen) Put(message *messages.Put) (bool, int) {
 	screen.Lock()
 	defer screen.Unlock()
 	defer screen.Render()
 
+	return screen.put(message)
+}
+
+func (screen *Screen) GetSize() (int, int) {
+	return screen.width, screen.height
+}
+
+func (screen *Screen) GetGrid() (int, int) {
+	return screen.columns, screen.rows
+}
+
+func (screen *Screen) GetArea() int {
+	return screen.columns * screen.rows
+}
+
+func (screen *Screen) Resize(width, height int) (int, int) {
+	screen.Lock()
+	defer screen.Unlock()
+
+	return screen.resize(width, height)
+}
+
+// SetFont changes font of the screen, so grid is recalculated and every
+// cell is redrawn using glyphs of new font. It returns new amount of rows
+// and columns.
+func (screen *Screen) SetFont(font *fonts.Font) (int, int) {
+	screen.Lock()
+	defer screen.Unlock()
+	defer screen.Render()
+
+	previous := screen.font
+
+	screen.font = font
+
+	for pos := range screen.attrs {
+		screen.remapGlyph(pos, previous)
+	}
+
+	return screen.resize(screen.width, screen.height)
+}
+
+func (screen *Screen) GetFont() *fonts.Font {
+	screen.Lock()
+	defer screen.Unlock()
+
+	return screen.font
+}
+
+func (screen *Screen) resize(width, height int) (int, int) {
 	var (
-		address = screen.getRegionID(message.X, message.Y)
+		columns = width / screen.font.GetWidth()
+		rows    = height / screen.font.GetHeight()
+
+		cells  = make([]int32, 2*rows*columns)
+		attrs  = make([]int32, rows*columns)
+		colors = make([]int32, 2*rows*columns)
+	)
+
+	for y := 0; y < screen.rows; y++ {
+		if y >= rows {
+			break
+		}
+
+		for x := 0; x < screen.columns; x++ {
+			if x >= columns {
+				break
+			}
+
+			var (
+				from = x + y*screen.columns
+				to   = x + y*columns
+			)
+
+			cells[to*2] = screen.cells[from*2]
+			cells[to*2+1] = screen.cells[from*2+1]
+			attrs[to] = screen.attrs[from]
+			colors[to*2] = screen.colors[from*2]
+			colors[to*2+1] = screen.colors[from*2+1]
+		}
+	}
+
+	screen.width = width
+	screen.height = height
+	screen.rows = rows
+	screen.columns = columns
+	screen.cells = cells
+	screen.attrs = attrs
+	screen.colors = colors
+
+	return rows, columns
+}
+
+func (screen *Screen) Clear(x int, y int, rows int, columns int) bool {
+	screen.Lock()
+	defer screen.Unlock()
+	defer screen.Render()
+
+	return screen.clear(x, y, rows, columns)
+}
+
+func (screen *Screen) Render() {
+	screen.render(screen)
+}
+
+func (screen *Screen) put(message *messages.Put) (bool, int) {
+	left, top := screen.resolve(message.X, message.Y)
+
+	var (
+		address = screen.getRegionID(left, top)
 		region  = screen.regions[address]
 	)
 
 	if region.Exclusive {
-		screen.clear(message.X, message.Y, region.Rows, region.Columns)
+		screen.clear(left, top, region.Rows, region.Columns)
 
 		region.Exclusive = false
 	}
 
 	var (
 		offscreen bool
+		replaced  int
 		columns   int
 		rows      int
 	)
@@ -122,54 +268,101 @@ func (screen *Screen) Put(message *messages.Put) bool {
 		delete(screen.regions, address)
 	}
 
+	screen.touchRegion(address)
+
+	// Style is replaced for every cell which receives new text, so text
+	// written without style flags is always plain.
+	var (
+		styled = message.Text != nil || message.IsStyled()
+		style  = getStyleAttrs(message)
+	)
+
 	if message.Text != nil {
 		text := *message.Text
 
 		if message.Columns == nil {
-			columns = len(text)
+			columns = getTextWidth(text)
 		}
 
-		var i int
+		var (
+			i int
+
+			// Every grapheme cluster, e.g. base char with combining
+			// accents, occupies single cell.
+			clusters = uniseg.NewGraphemes(text)
+		)
+
+		for clusters.Next() {
+			char := clusters.Runes()
+
+			if isLineBreak(char) {
+				i += (columns - i%columns)
+				continue
+			}
+
+			span := getClusterWidth(char)
+
+			// Double-width char can't be split between rows, so it's moved
+			// to the next row entirely.
+			if i%columns+span > columns && span <= columns {
+				i += (columns - i%columns)
+			}
 
-		for _, char := range text {
 			x := i % columns
 			y := i / columns
 			if y > rows {
 				break
 			}
 
-			if char == '\n' {
-				i += (columns - i%columns)
-				continue
-			}
+			x += left
+			y += top
 
-			x += message.X
-			y += message.Y
+			onscreen, missing := screen.set(
+				x,
+				y,
+				clusters.Str(),
+				style,
+				span > 1,
+			)
+
+			if missing {
+				replaced++
+			}
 
-			if !screen.set(x, y, string(char)) {
+			if !onscreen {
 				offscreen = true
 			} else {
 				if y >= region.Rows {
 					region.Rows = y + 1
 				}
 
-				if x >= region.Columns {
-					region.Columns = x + 1
+				if x+span > region.Columns {
+					region.Columns = x + span
 				}
 			}
 
-			i++
+			i += span
 		}
 	}
 
 	screen.regions[address] = region
 
+	if styled {
+		for y := 0; y < rows; y++ {
+			for x := 0; x < columns; x++ {
+				if !screen.setStyle(x+left, y+top, style) {
+					offscreen = true
+				}
+			}
+		}
+	}
+
 	if message.Foreground != nil {
 		for y := 0; y < rows; y++ {
 			for x := 0; x < columns; x++ {
 				if !screen.setForeground(
-					x+message.X,
-					y+message.Y,
+					x+left,
+					y+top,
 					message.Foreground,
 				) {
 					offscreen = true
@@ -182,8 +375,8 @@ func (screen *Screen) Put(message *messages.Put) bool {
 		for y := 0; y < rows; y++ {
 			for x := 0; x < columns; x++ {
 				if !screen.setBackground(
-					x+message.X,
-					y+message.Y,
+					x+left,
+					y+top,
 					message.Background,
 				) {
 					offscreen = true
@@ -192,173 +385,422 @@ func (screen *Screen) Put(message *messages.Put) bool {
 		}
 	}
 
-	return !offscreen
+	return !offscreen, replaced
 }
 
-func (screen *Screen) GetSize() (int, int) {
-	return screen.width, screen.height
-}
+func (screen *Screen) clear(x int, y int, rows int, columns int) bool {
+	var offscreen bool
 
-func (screen *Screen) GetGrid() (int, int) {
-	return screen.columns, screen.rows
-}
+	x, y = screen.resolve(x, y)
 
-func (screen *Screen) GetArea() int {
-	return screen.columns * screen.rows
-}
+	for i := 0; i < rows; i++ {
+		for j := 0; j < columns; j++ {
+			if !screen.contains(x+j, y+i) {
+				offscreen = true
+				continue
+			}
 
-func (screen *Screen) Resize(width, height int) (int, int) {
-	screen.Lock()
-	defer screen.Unlock()
+			pos := (x + j) + (y+i)*screen.columns
 
-	var (
-		columns = width / screen.font.GetWidth()
-		rows    = height / screen.font.GetHeight()
+			screen.unsetWide(pos)
+			screen.attrs[pos] = AttrEmpty
+			screen.touch(pos)
+		}
+	}
 
-		cells  = make([]int32, 2*rows*columns)
-		attrs  = make([]int32, rows*columns)
-		colors = make([]int32, 2*rows*columns)
-	)
+	return offscreen
 
-	for y := 0; y < screen.rows; y++ {
-		if y >= rows {
-			break
-		}
+}
 
-		for x := 0; x < screen.columns; x++ {
-			if x >= columns {
-				break
-			}
+func (screen *Screen) GetCells() []int32 {
+	return screen.cells
+}
 
-			var (
-				from = x + y*screen.columns
-				to   = x + y*columns
-			)
+func (screen *Screen) GetAttrs() []int32 {
+	return screen.attrs
+}
 
-			cells[to*2] = screen.cells[from*2]
-			cells[to*2+1] = screen.cells[from*2+1]
-			attrs[to] = screen.attrs[from]
-			colors[to*2] = screen.colors[from*2]
-			colors[to*2+1] = screen.colors[from*2+1]
-		}
+func (screen *Screen) GetColors() []int32 {
+	return screen.colors
+}
+
+// resolve converts negative coordinates, which are relative to right and
+// bottom screen edges, into absolute coordinates, so `-1` refers to last
+// column or row.
+func (screen *Screen) resolve(x int, y int) (int, int) {
+	if x < 0 {
+		x += screen.columns
 	}
 
-	screen.width = width
-	screen.height = height
-	screen.rows = rows
-	screen.columns = columns
-	screen.cells = cells
-	screen.attrs = attrs
-	screen.colors = colors
+	if y < 0 {
+		y += screen.rows
+	}
 
-	return rows, columns
+	return x, y
 }
 
-func (screen *Screen) Clear(x int, y int, rows int, columns int) bool {
+func (screen *Screen) contains(x int, y int) bool {
+	return x >= 0 && x < screen.columns && y >= 0 && y < screen.rows
+}
+
+// GetRegion returns origin and size of region of cells, which is specified
+// by optional values of command arguments.
+func (screen *Screen) GetRegion(
+	x *int,
+	y *int,
+	rows *int,
+	columns *int,
+) (int, int, int, int) {
 	screen.Lock()
 	defer screen.Unlock()
-	defer screen.Render()
 
-	return screen.clear(x, y, rows, columns)
+	return screen.getRegion(x, y, rows, columns)
 }
 
-func (screen *Screen) Render() {
-	screen.render(screen)
-}
+// getRegion returns region which starts at top left corner of screen and
+// spans to bottom right corner by default. Origin is not resolved, so
+// negative coordinates are returned as is.
+func (screen *Screen) getRegion(
+	x *int,
+	y *int,
+	rows *int,
+	columns *int,
+) (int, int, int, int) {
+	var left, top int
+
+	if x != nil {
+		left = *x
+	}
 
-func (screen *Screen) clear(x int, y int, rows int, columns int) bool {
-	var offscreen bool
+	if y != nil {
+		top = *y
+	}
 
-	for i := 0; i < rows; i++ {
-		for j := 0; j < columns; j++ {
-			if x+j >= screen.columns {
-				offscreen = true
-				continue
-			}
+	column, row := screen.resolve(left, top)
 
-			if y+i >= screen.rows {
-				offscreen = true
-				continue
-			}
+	var (
+		height = screen.rows - row
+		width  = screen.columns - column
+	)
 
-			screen.attrs[(x+j)+(y+i)*screen.columns] = AttrEmpty
-		}
+	if rows != nil {
+		height = *rows
 	}
 
-	return offscreen
+	if columns != nil {
+		width = *columns
+	}
 
+	return left, top, height, width
 }
 
-func (screen *Screen) GetCells() []int32 {
-	return screen.cells
-}
+// clip returns part of region of cells which is on screen, so loops over
+// region never visit offscreen cells. It returns false if some cells of
+// region are offscreen.
+func (screen *Screen) clip(
+	x int,
+	y int,
+	rows int,
+	columns int,
+) (image.Rectangle, bool) {
+	if rows <= 0 || columns <= 0 {
+		return image.ZR, true
+	}
 
-func (screen *Screen) GetAttrs() []int32 {
-	return screen.attrs
+	var (
+		left, right = clipSpan(x, columns, screen.columns)
+		top, bottom = clipSpan(y, rows, screen.rows)
+	)
+
+	return image.Rect(left, top, right, bottom),
+		right-left == columns && bottom-top == rows
 }
 
-func (screen *Screen) GetColors() []int32 {
-	return screen.colors
+// clipSpan returns bounds of part of span which is within [0, limit).
+// Size is compared with distance to limit before it's added to start, so
+// huge sizes requested by clients don't overflow.
+func clipSpan(start int, size int, limit int) (int, int) {
+	if start >= limit || start <= -size {
+		return 0, 0
+	}
+
+	end := limit
+
+	if start < 0 || size < limit-start {
+		end = start + size
+	}
+
+	if end > limit {
+		end = limit
+	}
+
+	if start < 0 {
+		start = 0
+	}
+
+	return start, end
 }
 
 func (screen *Screen) getRegionID(x int, y int) string {
 	return strconv.Itoa(x) + ":" + strconv.Itoa(y)
 }
 
-func (screen *Screen) set(x, y int, char string) bool {
-	if x >= screen.columns {
-		return false
+func (screen *Screen) set(
+	x int,
+	y int,
+	char string,
+	style int32,
+	wide bool,
+) (bool, bool) {
+	if !screen.contains(x, y) {
+		return false, false
 	}
 
-	if y >= screen.rows {
-		return false
+	pos := x + y*screen.columns
+
+	// Overwriting any half of double-width char erases another half.
+	screen.unsetWide(pos)
+
+	// Double-width char at last column is drawn clipped.
+	wide = wide && screen.contains(x+1, y)
+	if wide {
+		screen.unsetWide(pos + 1)
 	}
 
-	glyph := screen.font.Glyphs[char]
-	if glyph == nil {
-		// TODO: draw some missing char
-		return true
+	var (
+		glyph   = screen.font.GetGlyph(char, getFontStyle(style))
+		missing = glyph == nil
+	)
+
+	if missing {
+		glyph = screen.font.Replacement
 	}
 
-	pos := x + y*screen.columns
+	if wide {
+		screen.attrs[pos] |= AttrWide
+		screen.attrs[pos+1] |= AttrWideTail
+	}
+
+	screen.setGlyph(pos, glyph)
+
+	return true, missing
+}
 
+// setGlyph assigns glyph to cell at given position and to tail cell if
+// position is the beginning of double-width char.
+func (screen *Screen) setGlyph(pos int, glyph *fonts.Glyph) {
 	screen.cells[pos*2] = int32(glyph.Column)
 	screen.cells[pos*2+1] = int32(glyph.Row)
 	screen.attrs[pos] |= AttrGlyph
+	screen.touch(pos)
 
-	return true
+	tail := pos + 1
+
+	if screen.attrs[pos]&AttrWide == 0 || tail%screen.columns == 0 {
+		return
+	}
+
+	if screen.attrs[tail]&AttrWideTail == 0 {
+		return
+	}
+
+	// Font can lack double-width glyph for char which is wide according
+	// to East Asian Width, so tail cell is left blank.
+	if glyph.Wide {
+		screen.cells[tail*2] = int32(glyph.Column + 1)
+		screen.cells[tail*2+1] = int32(glyph.Row)
+		screen.attrs[tail] |= AttrGlyph
+	} else {
+		screen.attrs[tail] &^= AttrGlyph
+	}
+
+	screen.touch(tail)
 }
 
-func (screen *Screen) setForeground(x int, y int, fg *color.RGBA) bool {
-	if x >= screen.columns {
-		return false
+// remapGlyph replaces glyph in cell at given position, which refers to
+// atlas of given font, with glyph of the same char from screen font.
+func (screen *Screen) remapGlyph(pos int, font *fonts.Font) {
+	attrs := screen.attrs[pos]
+
+	// Tail cell is remapped along with beginning of double-width char.
+	if attrs&AttrGlyph == 0 || attrs&AttrWideTail != 0 {
+		return
+	}
+
+	glyph := font.GetGlyphAt(
+		int(screen.cells[pos*2]),
+		int(screen.cells[pos*2+1]),
+	)
+
+	switch {
+	case glyph == nil:
+		// Chars without outlines don't have own atlas cell.
+		screen.attrs[pos] &^= AttrGlyph
+		screen.touch(pos)
+
+		return
+
+	case glyph == font.Replacement:
+		glyph = screen.font.Replacement
+
+	default:
+		glyph = screen.font.GetGlyph(glyph.Char, getFontStyle(attrs))
+		if glyph == nil {
+			glyph = screen.font.Replacement
+		}
+	}
+
+	screen.setGlyph(pos, glyph)
+}
+
+// unsetWide erases both halves of double-width char if cell at given
+// position is part of it.
+func (screen *Screen) unsetWide(pos int) {
+	var (
+		attrs = screen.attrs[pos]
+		pair  int
+	)
+
+	switch {
+	case attrs&AttrWide != 0:
+		pair = pos + 1
+
+		// Tail can be cut off by resize.
+		if pair%screen.columns == 0 || screen.attrs[pair]&AttrWideTail == 0 {
+			pair = -1
+		}
+
+	case attrs&AttrWideTail != 0:
+		pair = pos - 1
+
+		if pos%screen.columns == 0 || screen.attrs[pair]&AttrWide == 0 {
+			pair = -1
+		}
+
+	default:
+		return
 	}
 
-	if y >= screen.rows {
+	screen.attrs[pos] &^= AttrGlyph | AttrWide | AttrWideTail
+	screen.touch(pos)
+
+	if pair >= 0 {
+		screen.attrs[pair] &^= AttrGlyph | AttrWide | AttrWideTail
+		screen.touch(pair)
+	}
+}
+
+func (screen *Screen) setStyle(x int, y int, style int32) bool {
+	if !screen.contains(x, y) {
 		return false
 	}
 
 	pos := x + y*screen.columns
 
-	screen.colors[pos*2] = int32(fg.R)<<16 + int32(fg.G)<<8 + int32(fg.B)
-	screen.attrs[pos] |= AttrForeground
+	screen.attrs[pos] = screen.attrs[pos]&^AttrStyle | style
+	screen.touch(pos)
 
 	return true
 }
 
-func (screen *Screen) setBackground(x, y int, bg *color.RGBA) bool {
-	if x >= screen.columns {
+func (screen *Screen) setForeground(x int, y int, fg *messages.Color) bool {
+	if !screen.contains(x, y) {
 		return false
 	}
 
-	if y >= screen.rows {
+	pos := x + y*screen.columns
+
+	screen.colors[pos*2] = packIndexedColor(fg)
+	screen.attrs[pos] |= AttrForeground
+
+	if fg.Indexed {
+		screen.attrs[pos] |= AttrForegroundIndexed
+	} else {
+		screen.attrs[pos] &^= AttrForegroundIndexed
+	}
+	screen.touch(pos)
+
+	return true
+}
+
+func (screen *Screen) setBackground(x, y int, bg *messages.Color) bool {
+	if !screen.contains(x, y) {
 		return false
 	}
 
 	pos := x + y*screen.columns
 
-	screen.colors[pos*2+1] = int32(bg.R)<<16 + int32(bg.G)<<8 + int32(bg.B)
+	screen.colors[pos*2+1] = packIndexedColor(bg)
 	screen.attrs[pos] |= AttrBackground
 
+	if bg.Indexed {
+		screen.attrs[pos] |= AttrBackgroundIndexed
+	} else {
+		screen.attrs[pos] &^= AttrBackgroundIndexed
+	}
+	screen.touch(pos)
+
 	return true
 }
+
+// getStyleAttrs converts style flags of put message into cell attributes.
+func getStyleAttrs(message *messages.Put) int32 {
+	var style int32
+
+	flags := []struct {
+		set  bool
+		attr int32
+	}{
+		{message.Bold, AttrBold},
+		{message.Italic, AttrItalic},
+		{message.Underline, AttrUnderline},
+		{message.Strike, AttrStrike},
+		{message.Reverse, AttrReverse},
+		{message.Dim, AttrDim},
+	}
+
+	for _, flag := range flags {
+		if flag.set {
+			style |= flag.attr
+		}
+	}
+
+	return style
+}
+
+// getFontStyle returns font face which should be used for glyph with
+// given style attributes.
+func getFontStyle(style int32) fonts.Style {
+	var face fonts.Style
+
+	if style&AttrBold != 0 {
+		face |= fonts.StyleBold
+	}
+
+	if style&AttrItalic != 0 {
+		face |= fonts.StyleItalic
+	}
+
+	return face
+}
+
+// packIndexedColor packs either palette index or RGBA color, depending on
+// color kind.
+func packIndexedColor(color *messages.Color) int32 {
+	if color.Indexed {
+		return int32(color.Index)
+	}
+
+	return packColor(&color.RGBA)
+}
+
+// packColor packs color into single integer in 0xRRGGBBAA form, which is
+// unpacked back in fragment shader.
+func packColor(color *color.RGBA) int32 {
+	return int32(
+		uint32(color.R)<<24 |
+			uint32(color.G)<<16 |
+			uint32(color.B)<<8 |
+			uint32(color.A),
+	)
+}
@@ -0,0 +1,165 @@
+package engine
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// composite draws cells of screen into given image in software, doing the
+// same as fragment shader does: glyph alpha is taken from font atlas and
+// blended between foreground and background colors of the cell.
+//
+// Screen should be locked.
+func (screen *Screen) composite(
+	target *image.RGBA,
+	foreground color.RGBA,
+	background color.RGBA,
+	palette Palette,
+) {
+	// Framebuffer is cleared with default background first, same as in GL,
+	// so empty cells and area outside of grid show default background.
+	draw.Draw(
+		target,
+		target.Bounds(),
+		image.NewUniform(color.NRGBA(background)),
+		image.ZP,
+		draw.Src,
+	)
+
+	font := screen.font
+
+	// Atlas can be grown by other window which shares the same font.
+	font.Lock()
+	defer font.Unlock()
+
+	var (
+		width    = font.GetWidth()
+		height   = font.GetHeight()
+		baseline = font.GetBaseline()
+		atlas    = font.Image
+
+		// Underline is drawn one pixel below baseline and strikethrough
+		// in the middle between top of the cell and baseline.
+		underline = baseline + 1
+		strike    = baseline * 2 / 3
+	)
+
+	if underline > height-1 {
+		underline = height - 1
+	}
+
+	resolve := func(packed int32, indexed bool) color.RGBA {
+		if indexed {
+			return palette[packed]
+		}
+
+		return unpackColor(packed)
+	}
+
+	for pos, attrs := range screen.attrs {
+		if attrs == AttrEmpty {
+			continue
+		}
+
+		var (
+			fg = foreground
+			bg = background
+
+			cell = image.Pt(
+				pos%screen.columns*width,
+				pos/screen.columns*height,
+			)
+
+			glyph = image.Pt(
+				int(screen.cells[pos*2])*width,
+				int(screen.cells[pos*2+1])*height,
+			)
+		)
+
+		if attrs&AttrForeground != 0 {
+			fg = resolve(
+				screen.colors[pos*2],
+				attrs&AttrForegroundIndexed != 0,
+			)
+		}
+
+		if attrs&AttrBackground != 0 {
+			bg = resolve(
+				screen.colors[pos*2+1],
+				attrs&AttrBackgroundIndexed != 0,
+			)
+		}
+
+		if attrs&AttrReverse != 0 {
+			fg, bg = bg, fg
+		}
+
+		if attrs&AttrDim != 0 {
+			fg.A /= 2
+		}
+
+		for y := 0; y < height; y++ {
+			line := attrs&AttrUnderline != 0 && y == underline ||
+				attrs&AttrStrike != 0 && y == strike
+
+			for x := 0; x < width; x++ {
+				var alpha float64
+
+				switch {
+				case line:
+					alpha = 1
+
+				case attrs&AttrGlyph != 0:
+					alpha = float64(
+						atlas.RGBAAt(glyph.X+x, glyph.Y+y).A,
+					) / 0xff
+				}
+
+				blend(target, cell.X+x, cell.Y+y, fg, bg, alpha)
+			}
+		}
+	}
+}
+
+// blend draws pixel of given glyph coverage over target pixel. Glyph is
+// drawn over cell background and then cell is drawn over framebuffer, so
+// result is premultiplied, same as in GL framebuffer.
+func blend(
+	target *image.RGBA,
+	x int,
+	y int,
+	fg color.RGBA,
+	bg color.RGBA,
+	alpha float64,
+) {
+	if !(image.Point{x, y}.In(target.Rect)) {
+		return
+	}
+
+	var (
+		coverage = alpha * float64(fg.A) / 0xff
+		covered  = float64(bg.A) / 0xff * (1 - coverage)
+		opacity  = coverage + covered
+
+		foreground = [3]uint8{fg.R, fg.G, fg.B}
+		background = [3]uint8{bg.R, bg.G, bg.B}
+
+		offset = target.PixOffset(x, y)
+		pixel  = target.Pix[offset : offset+4 : offset+4]
+	)
+
+	if opacity == 0 {
+		return
+	}
+
+	for i := range foreground {
+		value := float64(foreground[i])*coverage +
+			float64(background[i])*covered +
+			float64(pixel[i])*(1-opacity)
+
+		pixel[i] = uint8(value + 0.5)
+	}
+
+	pixel[3] = uint8(opacity*0xff + float64(pixel[3])*(1-opacity) + 0.5)
+}
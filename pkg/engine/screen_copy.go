@@ -0,0 +1,156 @@
+package engine
+
+import "image"
+
+// Copy copies region of cells at given position to another position on the
+// same screen. If clear is true, then cells of source region which are not
+// overwritten by copied cells are cleared, so region is moved. It returns
+// false if some cells of source or target region are offscreen.
+func (screen *Screen) Copy(
+	x int,
+	y int,
+	rows int,
+	columns int,
+	toX int,
+	toY int,
+	clear bool,
+) bool {
+	screen.Lock()
+	defer screen.Unlock()
+	defer screen.Render()
+
+	return screen.copy(x, y, rows, columns, toX, toY, clear)
+}
+
+// copy copies glyphs, attributes and colors of cells. Source and target
+// regions can overlap, so source cells are saved before any cell is
+// changed. Regions are clipped to screen, so cells which are offscreen in
+// either region are skipped.
+func (screen *Screen) copy(
+	x int,
+	y int,
+	rows int,
+	columns int,
+	toX int,
+	toY int,
+	clear bool,
+) bool {
+	type cell struct {
+		pos    int
+		glyph  [2]int32
+		attrs  int32
+		colors [2]int32
+	}
+
+	x, y = screen.resolve(x, y)
+	toX, toY = screen.resolve(toX, toY)
+
+	var (
+		source, sourceOnscreen = screen.clip(x, y, rows, columns)
+		target, targetOnscreen = screen.clip(toX, toY, rows, columns)
+
+		onscreen = sourceOnscreen && targetOnscreen
+
+		// copied is region of offsets within requested region which are
+		// onscreen in both source and target regions.
+		copied image.Rectangle
+
+		sources = []int{}
+		targets = map[int]bool{}
+		cells   = []cell{}
+	)
+
+	for row := source.Min.Y; row < source.Max.Y; row++ {
+		for column := source.Min.X; column < source.Max.X; column++ {
+			sources = append(sources, column+row*screen.columns)
+		}
+	}
+
+	if !source.Empty() && !target.Empty() {
+		copied = source.Sub(image.Pt(x, y)).Intersect(
+			target.Sub(image.Pt(toX, toY)),
+		)
+	}
+
+	for i := copied.Min.Y; i < copied.Max.Y; i++ {
+		for j := copied.Min.X; j < copied.Max.X; j++ {
+			var (
+				from = (x + j) + (y+i)*screen.columns
+				to   = (toX + j) + (toY+i)*screen.columns
+			)
+
+			targets[to] = true
+
+			cells = append(cells, cell{
+				pos: to,
+				glyph: [2]int32{
+					screen.cells[from*2],
+					screen.cells[from*2+1],
+				},
+				attrs: screen.attrs[from],
+				colors: [2]int32{
+					screen.colors[from*2],
+					screen.colors[from*2+1],
+				},
+			})
+		}
+	}
+
+	if clear {
+		for _, pos := range sources {
+			if targets[pos] {
+				continue
+			}
+
+			screen.unsetWide(pos)
+			screen.attrs[pos] = AttrEmpty
+			screen.touch(pos)
+		}
+	}
+
+	// Double-width chars which are partially overwritten are erased before
+	// copying, otherwise their halves can be mistaken for halves of copied
+	// chars.
+	for _, cell := range cells {
+		screen.unsetWide(cell.pos)
+	}
+
+	for _, cell := range cells {
+		screen.cells[cell.pos*2] = cell.glyph[0]
+		screen.cells[cell.pos*2+1] = cell.glyph[1]
+		screen.attrs[cell.pos] = cell.attrs
+		screen.colors[cell.pos*2] = cell.colors[0]
+		screen.colors[cell.pos*2+1] = cell.colors[1]
+
+		screen.touch(cell.pos)
+	}
+
+	for _, cell := range cells {
+		screen.unsetHalf(cell.pos)
+	}
+
+	return onscreen
+}
+
+// unsetHalf fixes cell at given position if it's half of double-width char
+// which other half is not copied along with it. Beginning of char is drawn
+// clipped, like double-width char at last column, and tail is erased.
+func (screen *Screen) unsetHalf(pos int) {
+	attrs := screen.attrs[pos]
+
+	switch {
+	case attrs&AttrWide != 0:
+		tail := pos + 1
+
+		if tail%screen.columns == 0 || screen.attrs[tail]&AttrWideTail == 0 {
+			screen.attrs[pos] &^= AttrWide
+		}
+
+	case attrs&AttrWideTail != 0:
+		head := pos - 1
+
+		if pos%screen.columns == 0 || screen.attrs[head]&AttrWide == 0 {
+			screen.attrs[pos] &^= AttrGlyph | AttrWideTail
+		}
+	}
+}
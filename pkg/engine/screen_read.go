@@ -0,0 +1,162 @@
+package engine
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/seletskiy/mainframe/pkg/protocol/messages"
+)
+
+// ScreenCell is contents of single cell which is read back from screen.
+type ScreenCell struct {
+	// Char is text of cell. It is empty for right half of double-width
+	// char and space for cells without glyph.
+	Char string
+
+	// Foreground and Background are nil if cell has default colors.
+	Foreground *messages.Color
+	Background *messages.Color
+
+	// Style holds style attributes of cell, like AttrBold.
+	Style int32
+}
+
+// Read returns cells of given region, row by row, and position of first
+// returned cell. Region is clipped to screen bounds, so it returns false if
+// some cells are offscreen.
+func (screen *Screen) Read(
+	x int,
+	y int,
+	rows int,
+	columns int,
+) ([][]ScreenCell, image.Point, bool) {
+	screen.Lock()
+	defer screen.Unlock()
+
+	x, y = screen.resolve(x, y)
+
+	var (
+		region, onscreen = screen.clip(x, y, rows, columns)
+
+		origin = image.Pt(x, y)
+		cells  = [][]ScreenCell{}
+	)
+
+	// Cells are clipped to screen, so they start at first onscreen cell.
+	if origin.X < 0 {
+		origin.X = 0
+	}
+
+	if origin.Y < 0 {
+		origin.Y = 0
+	}
+
+	if region.Empty() {
+		return cells, origin, onscreen
+	}
+
+	for y := region.Min.Y; y < region.Max.Y; y++ {
+		row := make([]ScreenCell, 0, region.Dx())
+
+		for x := region.Min.X; x < region.Max.X; x++ {
+			row = append(row, screen.read(x+y*screen.columns))
+		}
+
+		cells = append(cells, row)
+	}
+
+	return cells, origin, onscreen
+}
+
+// GetBounds returns rectangle in pixels which is occupied by region of
+// cells, which is specified by optional values of command arguments like
+// in GetRegion. Region is clipped to screen bounds, so it returns false if
+// some cells are offscreen.
+func (screen *Screen) GetBounds(
+	x *int,
+	y *int,
+	rows *int,
+	columns *int,
+) (image.Rectangle, bool) {
+	screen.Lock()
+	defer screen.Unlock()
+
+	left, top, height, width := screen.getRegion(x, y, rows, columns)
+
+	left, top = screen.resolve(left, top)
+
+	var (
+		region, onscreen = screen.clip(left, top, height, width)
+
+		cellWidth  = screen.font.GetWidth()
+		cellHeight = screen.font.GetHeight()
+	)
+
+	return image.Rect(
+		region.Min.X*cellWidth,
+		region.Min.Y*cellHeight,
+		region.Max.X*cellWidth,
+		region.Max.Y*cellHeight,
+	), onscreen
+}
+
+func (screen *Screen) read(pos int) ScreenCell {
+	var (
+		attrs = screen.attrs[pos]
+		cell  = ScreenCell{
+			Char:  " ",
+			Style: attrs & AttrStyle,
+		}
+	)
+
+	switch {
+	case attrs&AttrWideTail != 0:
+		cell.Char = ""
+
+	case attrs&AttrGlyph != 0:
+		// Chars without outlines refer to blank atlas cell, which has no
+		// glyph, so they are read as space.
+		glyph := screen.font.GetGlyphAt(
+			int(screen.cells[pos*2]),
+			int(screen.cells[pos*2+1]),
+		)
+		if glyph != nil {
+			cell.Char = glyph.Char
+		}
+	}
+
+	if attrs&AttrForeground != 0 {
+		cell.Foreground = unpackIndexedColor(
+			screen.colors[pos*2],
+			attrs&AttrForegroundIndexed != 0,
+		)
+	}
+
+	if attrs&AttrBackground != 0 {
+		cell.Background = unpackIndexedColor(
+			screen.colors[pos*2+1],
+			attrs&AttrBackgroundIndexed != 0,
+		)
+	}
+
+	return cell
+}
+
+// unpackIndexedColor is reverse of packIndexedColor.
+func unpackIndexedColor(value int32, indexed bool) *messages.Color {
+	if indexed {
+		return messages.NewIndexedColor(int(value))
+	}
+
+	return messages.NewRGBAColor(unpackColor(value))
+}
+
+// unpackColor is reverse of packColor.
+func unpackColor(value int32) color.RGBA {
+	return color.RGBA{
+		R: uint8(uint32(value) >> 24),
+		G: uint8(uint32(value) >> 16),
+		B: uint8(uint32(value) >> 8),
+		A: uint8(uint32(value)),
+	}
+}
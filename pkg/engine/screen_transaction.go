@@ -0,0 +1,114 @@
+package engine
+
+// Begin creates shadow copy of screen. All changes made to shadow copy are
+// invisible until shadow is committed back into screen via Commit.
+//
+// Shadow copy can be used to begin nested transaction as well.
+func (screen *Screen) Begin() *Screen {
+	screen.Lock()
+	defer screen.Unlock()
+
+	shadow := &Screen{
+		width:  screen.width,
+		height: screen.height,
+
+		columns: screen.columns,
+		rows:    screen.rows,
+
+		font: screen.font,
+
+		cells:  make([]int32, len(screen.cells)),
+		attrs:  make([]int32, len(screen.attrs)),
+		colors: make([]int32, len(screen.colors)),
+
+		regions: make(map[string]ScreenRegion, len(screen.regions)),
+
+		// Shadow copy is never rendered, it's changes will be rendered
+		// after commit.
+		render: func(*Screen) {},
+	}
+
+	copy(shadow.cells, screen.cells)
+	copy(shadow.attrs, screen.attrs)
+	copy(shadow.colors, screen.colors)
+
+	for address, region := range screen.regions {
+		shadow.regions[address] = region
+	}
+
+	shadow.changes.cells = map[int]bool{}
+	shadow.changes.regions = map[string]bool{}
+
+	return shadow
+}
+
+// Commit copies only cells that were changed in shadow copy into screen and
+// renders screen once.
+//
+// Since only changed cells are copied, transactions started by different
+// clients will not overwrite each other changes unless they touch same cells.
+func (screen *Screen) Commit(shadow *Screen) {
+	shadow.Lock()
+	defer shadow.Unlock()
+
+	screen.Lock()
+	defer screen.Unlock()
+	defer screen.Render()
+
+	var copied []int
+
+	for from := range shadow.changes.cells {
+		var (
+			x = from % shadow.columns
+			y = from / shadow.columns
+		)
+
+		// Screen can be resized while transaction is in progress.
+		if x >= screen.columns || y >= screen.rows {
+			continue
+		}
+
+		to := x + y*screen.columns
+
+		screen.cells[to*2] = shadow.cells[from*2]
+		screen.cells[to*2+1] = shadow.cells[from*2+1]
+		screen.attrs[to] = shadow.attrs[from]
+		screen.colors[to*2] = shadow.colors[from*2]
+		screen.colors[to*2+1] = shadow.colors[from*2+1]
+
+		screen.touch(to)
+
+		copied = append(copied, to)
+	}
+
+	// Font can be changed while transaction is in progress. Cells are
+	// remapped after all of them are copied, so both halves of
+	// double-width chars are in place.
+	if shadow.font != screen.font {
+		for _, pos := range copied {
+			screen.remapGlyph(pos, shadow.font)
+		}
+	}
+
+	for address := range shadow.changes.regions {
+		if region, ok := shadow.regions[address]; ok {
+			screen.regions[address] = region
+		} else {
+			delete(screen.regions, address)
+		}
+
+		screen.touchRegion(address)
+	}
+}
+
+func (screen *Screen) touch(pos int) {
+	if screen.changes.cells != nil {
+		screen.changes.cells[pos] = true
+	}
+}
+
+func (screen *Screen) touchRegion(address string) {
+	if screen.changes.regions != nil {
+		screen.changes.regions[address] = true
+	}
+}
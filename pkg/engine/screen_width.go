@@ -0,0 +1,51 @@
+package engine
+
+import (
+	"github.com/rivo/uniseg"
+	"golang.org/x/text/width"
+)
+
+// getRuneWidth returns amount of cells which given rune occupies on screen
+// according to East Asian Width property: wide and fullwidth runes occupy
+// two cells, while all other runes, including ambiguous, occupy one cell.
+func getRuneWidth(char rune) int {
+	switch width.LookupRune(char).Kind() {
+	case width.EastAsianWide, width.EastAsianFullwidth:
+		return 2
+	default:
+		return 1
+	}
+}
+
+// getClusterWidth returns amount of cells which given grapheme cluster
+// occupies on screen, which is determined by its base char.
+func getClusterWidth(cluster []rune) int {
+	return getRuneWidth(cluster[0])
+}
+
+// isLineBreak returns true if given grapheme cluster breaks line. CRLF is
+// single cluster, so newline is not necessarily first char of cluster.
+func isLineBreak(cluster []rune) bool {
+	for _, char := range cluster {
+		if char == '\n' {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getTextWidth returns amount of cells which given text occupies on screen
+// when put on single row.
+func getTextWidth(text string) int {
+	var (
+		length   = 0
+		clusters = uniseg.NewGraphemes(text)
+	)
+
+	for clusters.Next() {
+		length += getClusterWidth(clusters.Runes())
+	}
+
+	return length
+}
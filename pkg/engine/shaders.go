@@ -1,3 +1,6 @@
+//go:build !headless
+// +build !headless
+
 package engine
 
 var vertexShader = `
@@ -111,6 +114,43 @@ var fragmentShader = `
 	// [static] uni_Font: font texture.
 	uniform sampler2D uni_Font;
 
+	// [static] uni_Baseline: distance from top of the cell to glyph
+	// baseline in pixels.
+	uniform int uni_Baseline;
+
+	// [static] uni_Foreground: default foreground color.
+	uniform vec4 uni_Foreground;
+
+	// [static] uni_Background: default background color, which is
+	// transparent for windows with transparent framebuffer by default.
+	uniform vec4 uni_Background;
+
+	// [static] uni_Palette: window palette of 256 colors packed as
+	// 0xRRGGBBAA, 4 colors per array item.
+	uniform uvec4 uni_Palette[64];
+
+	// unpackColor converts color packed as 0xRRGGBBAA into vec4.
+	vec4 unpackColor(int packed) {
+		uint color = uint(packed);
+
+		return vec4(
+			float((color >> 24) & 0xffu),
+			float((color >> 16) & 0xffu),
+			float((color >>  8) & 0xffu),
+			float((color >>  0) & 0xffu)
+		) / 0xff;
+	}
+
+	// resolveColor returns either color from palette if indexed flag is
+	// set or unpacks color directly.
+	vec4 resolveColor(int packed, bool indexed) {
+		if (indexed) {
+			return unpackColor(int(uni_Palette[packed / 4][packed % 4]));
+		}
+
+		return unpackColor(packed);
+	}
+
 	// We have pixel coordinates, font texture and top-left corner of
 	// current cell.
 	//
@@ -132,24 +172,28 @@ var fragmentShader = `
 		}
 
 		float alpha = 0;
-		
-		if ((frag_Attrs & 1) != 0) {
-			// We first calculate pixel coordinates in coordinate system of
-			// current cell, e.g. from [0; 0] to [glyph width; glyph height].
-			//
-			// Because frag_Cell coorinates use top left corner as origin,
-			// we need to inverse y-part.
-			vec2 coord = (
-				vec2(gl_FragCoord.x, uni_ViewSize.y - gl_FragCoord.y) -
-				frag_Cell
-			);
 
+		// We first calculate pixel coordinates in coordinate system of
+		// current cell, e.g. from [0; 0] to [glyph width; glyph height].
+		//
+		// Because frag_Cell coorinates use top left corner as origin,
+		// we need to inverse y-part.
+		vec2 coord = (
+			vec2(gl_FragCoord.x, uni_ViewSize.y - gl_FragCoord.y) -
+			frag_Cell
+		);
+
+		if ((frag_Attrs & 1) != 0) {
 			// To obtain pixel color from font we do all calculations in pixels
 			// and then convert them to texture-coords.
 			//
 			// frag_Glyph * uni_GlyphSize - origin of current glyph in texture,
 			// coord - offset of pixel in glyph-local coordinates.
 			//
+			// Double-width glyph spans two adjacent cells in texture and
+			// each half of it is drawn as separate cell, so tail cell
+			// refers to right half of glyph via frag_Glyph.
+			//
 			// We currently interested only in alpha channel.
 			alpha = texture(
 				uni_Font,
@@ -157,30 +201,64 @@ var fragmentShader = `
 			).a;
 		}
 
-		// TODO: pass default foreground color.
-		// TODO: pass default background color.
-		vec3 fg = vec3(1.0, 1.0, 1.0);
-		vec3 bg = vec3(0.0, 0.0, 0.0);
+		vec4 fg = uni_Foreground;
+		vec4 bg = uni_Background;
 
-		// Flag '2' means that foreground color is set.
+		// Flag '2' means that foreground color is set and flag '8' means
+		// that it is palette index.
 		if ((frag_Attrs & 2) != 0) {
-			fg = vec3(
-				float((frag_Colors.x & 0xff0000) >> 16) / 0xff,
-				float((frag_Colors.x & 0x00ff00) >>  8) / 0xff,
-				float((frag_Colors.x & 0x0000ff) >>  0) / 0xff
-			);
+			fg = resolveColor(frag_Colors.x, (frag_Attrs & 8) != 0);
 		}
 
-		// Flag '4' means that background color is set.
+		// Flag '4' means that background color is set and flag '16' means
+		// that it is palette index.
 		if ((frag_Attrs & 4) != 0) {
-			bg = vec3(
-				float((frag_Colors.y & 0xff0000) >> 16) / 0xff,
-				float((frag_Colors.y & 0x00ff00) >>  8) / 0xff,
-				float((frag_Colors.y & 0x0000ff) >>  0) / 0xff
-			);
+			bg = resolveColor(frag_Colors.y, (frag_Attrs & 16) != 0);
+		}
+
+		// Flag '128' means underline, which is drawn one pixel below
+		// baseline, and flag '256' means strikethrough, which is drawn
+		// in the middle between top of the cell and baseline.
+		int line = int(coord.y);
+
+		if ((frag_Attrs & 128) != 0) {
+			if (line == min(uni_Baseline + 1, uni_GlyphSize.y - 1)) {
+				alpha = 1;
+			}
+		}
+
+		if ((frag_Attrs & 256) != 0) {
+			if (line == uni_Baseline * 2 / 3) {
+				alpha = 1;
+			}
+		}
+
+		// Flag '512' means reverse video, so foreground and background
+		// colors are swapped.
+		if ((frag_Attrs & 512) != 0) {
+			vec4 swap = fg;
+			fg = bg;
+			bg = swap;
+		}
+
+		// Flag '1024' means dim text, which is drawn with half of
+		// foreground opacity.
+		if ((frag_Attrs & 1024) != 0) {
+			fg.a *= 0.5;
+		}
+
+		// Glyph is drawn over background using standard 'over' operator,
+		// so both foreground and background can be translucent.
+		float coverage = alpha * fg.a;
+		float opacity = coverage + bg.a * (1 - coverage);
+
+		if (opacity == 0) {
+			discard;
 		}
 
-		out_Color = vec4(fg * alpha + bg * (1 - alpha), 1.0);
-		out_Color.a = 1.0;
+		out_Color = vec4(
+			(fg.rgb * coverage + bg.rgb * bg.a * (1 - coverage)) / opacity,
+			opacity
+		);
 	}
 `
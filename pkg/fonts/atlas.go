@@ -0,0 +1,137 @@
+package fonts
+
+import (
+	"image"
+	"image/draw"
+)
+
+const (
+	// AtlasColumns is amount of cells in single row of atlas.
+	AtlasColumns = 64
+
+	// AtlasRows is initial amount of rows in atlas. Atlas height is doubled
+	// every time there is no free space left for new glyph.
+	AtlasRows = 16
+)
+
+// AtlasStats describes usage of font atlas.
+type AtlasStats struct {
+	// Glyphs is amount of glyphs rasterized into atlas.
+	Glyphs int
+
+	// Width and Height are size of atlas image in pixels.
+	Width  int
+	Height int
+
+	// Cells is total amount of cells in atlas and Used is amount of cells
+	// occupied by glyphs.
+	Cells int
+	Used  int
+}
+
+type atlas struct {
+	columns int
+	row     int
+	column  int
+
+	// glyphs maps position of glyph in atlas back to glyph.
+	glyphs map[image.Point]*Glyph
+
+	// changes holds regions of atlas image which were changed since last
+	// flush.
+	changes []image.Rectangle
+}
+
+func (atlas *atlas) add(glyph *Glyph) {
+	if atlas.glyphs == nil {
+		atlas.glyphs = make(map[image.Point]*Glyph)
+	}
+
+	atlas.glyphs[image.Pt(glyph.Column, glyph.Row)] = glyph
+}
+
+func (atlas *atlas) changed(region image.Rectangle) {
+	atlas.changes = append(atlas.changes, region)
+}
+
+// Flush returns regions of atlas image which were changed since previous
+// call, so only these regions should be uploaded into texture. Atlas image
+// can be grown as well, in which case it should be uploaded entirely.
+//
+// Font should be locked while changes and atlas image are read.
+func (font *Font) Flush() []image.Rectangle {
+	changes := font.atlas.changes
+
+	font.atlas.changes = nil
+
+	return changes
+}
+
+// GetGlyphAt returns glyph located at given atlas cell. Nil is returned for
+// empty cells and for right halves of double-width glyphs.
+func (font *Font) GetGlyphAt(column int, row int) *Glyph {
+	font.Lock()
+	defer font.Unlock()
+
+	return font.atlas.glyphs[image.Pt(column, row)]
+}
+
+// GetAtlasStats returns current usage of font atlas.
+func (font *Font) GetAtlasStats() AtlasStats {
+	font.Lock()
+	defer font.Unlock()
+
+	var (
+		size = font.Image.Bounds().Size()
+		rows = size.Y / font.metrics.height
+	)
+
+	return AtlasStats{
+		Glyphs: len(font.atlas.glyphs),
+		Width:  size.X,
+		Height: size.Y,
+		Cells:  rows * font.atlas.columns,
+		Used:   font.atlas.row*font.atlas.columns + font.atlas.column,
+	}
+}
+
+// peek returns position of next free cells in atlas without reserving
+// them. Atlas image is grown if there is no free space left.
+func (font *Font) peek(span int) (int, int) {
+	var (
+		row    = font.atlas.row
+		column = font.atlas.column
+	)
+
+	if column+span > font.atlas.columns {
+		row++
+		column = 0
+	}
+
+	height := (row + 1) * font.metrics.height
+	if height > font.Image.Bounds().Dy() {
+		font.grow(font.Image.Bounds().Dy() * 2)
+	}
+
+	return row, column
+}
+
+// allocate reserves given amount of adjacent cells in atlas.
+func (font *Font) allocate(span int) (int, int) {
+	row, column := font.peek(span)
+
+	font.atlas.row = row
+	font.atlas.column = column + span
+
+	return row, column
+}
+
+func (font *Font) grow(height int) {
+	atlas := image.NewRGBA(
+		image.Rect(0, 0, font.Image.Bounds().Dx(), height),
+	)
+
+	draw.Draw(atlas, font.Image.Bounds(), font.Image, image.ZP, draw.Src)
+
+	font.Image = atlas
+}
@@ -0,0 +1,248 @@
+package fonts
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"image"
+	"strconv"
+	"strings"
+
+	"github.com/reconquest/karma-go"
+)
+
+// parseBDF parses font in Glyph Bitmap Distribution Format. Glyph
+// encodings are treated as Unicode code points, so only fonts with
+// ISO10646 or ISO8859-1 charset are displayed correctly.
+func parseBDF(body []byte, options rasterOptions) (*bitmap, error) {
+	var (
+		face    = newBitmap()
+		scanner = bufio.NewScanner(bytes.NewReader(body))
+
+		// Font bounding box is used as default for fonts without declared
+		// ascent and descent.
+		box image.Rectangle
+
+		ascent  = -1
+		descent = -1
+
+		line int
+	)
+
+	for scanner.Scan() {
+		line++
+
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		var err error
+
+		switch fields[0] {
+		case "SIZE":
+			if len(fields) > 2 && face.resolution == 0 {
+				face.resolution, err = strconv.Atoi(fields[2])
+			}
+
+		case "RESOLUTION_X":
+			if len(fields) > 1 {
+				face.resolution, err = strconv.Atoi(fields[1])
+			}
+
+		case "FONTBOUNDINGBOX":
+			box, err = parseBDFBox(fields)
+
+		case "FONT_ASCENT":
+			if len(fields) > 1 {
+				ascent, err = strconv.Atoi(fields[1])
+			}
+
+		case "FONT_DESCENT":
+			if len(fields) > 1 {
+				descent, err = strconv.Atoi(fields[1])
+			}
+
+		case "STARTCHAR":
+			err = parseBDFChar(scanner, face, &line)
+		}
+
+		if err != nil {
+			return nil, karma.
+				Describe("line", line).
+				Format(
+					err,
+					"unable to parse BDF font",
+				)
+		}
+	}
+
+	err := scanner.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(face.glyphs) == 0 {
+		return nil, karma.Format(
+			ErrMalformedFont,
+			"BDF font has no glyphs",
+		)
+	}
+
+	if ascent < 0 {
+		ascent = -box.Min.Y
+	}
+
+	if descent < 0 {
+		descent = box.Max.Y
+	}
+
+	face.ascent = ascent
+	face.descent = descent
+
+	face.setScale(options)
+
+	return face, nil
+}
+
+// parseBDFChar parses glyph definition from STARTCHAR to ENDCHAR. Glyphs
+// without Unicode encoding are skipped.
+func parseBDFChar(scanner *bufio.Scanner, face *bitmap, line *int) error {
+	var (
+		char    = rune(-1)
+		advance = -1
+		bounds  *image.Rectangle
+		bits    []byte
+		stride  int
+	)
+
+	for scanner.Scan() {
+		*line++
+
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		var err error
+
+		switch fields[0] {
+		case "ENCODING":
+			if len(fields) > 1 {
+				var code int
+
+				code, err = strconv.Atoi(fields[1])
+
+				char = rune(code)
+			}
+
+		case "DWIDTH":
+			if len(fields) > 1 {
+				advance, err = strconv.Atoi(fields[1])
+			}
+
+		case "BBX":
+			var box image.Rectangle
+
+			box, err = parseBDFBox(fields)
+
+			bounds = &box
+
+		case "BITMAP":
+			if bounds == nil {
+				return karma.Format(
+					ErrMalformedFont,
+					"BITMAP is specified before BBX",
+				)
+			}
+
+			stride = (bounds.Dx() + 7) / 8
+
+			for row := 0; row < bounds.Dy() && scanner.Scan(); row++ {
+				*line++
+
+				var data []byte
+
+				data, err = hex.DecodeString(strings.TrimSpace(scanner.Text()))
+				if err != nil {
+					break
+				}
+
+				// Rows can be padded to more bytes than needed.
+				padded := make([]byte, stride)
+				copy(padded, data)
+
+				bits = append(bits, padded...)
+			}
+
+		case "ENDCHAR":
+			if bounds == nil {
+				return karma.Format(
+					ErrMalformedFont,
+					"glyph has no BBX",
+				)
+			}
+
+			// Glyph without declared advance occupies its bounding box.
+			if advance < 0 {
+				advance = bounds.Max.X
+			}
+
+			if char >= 0 {
+				face.add(char, advance, *bounds, bits, stride)
+			}
+
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return karma.Format(
+		ErrMalformedFont,
+		"unexpected end of BDF font, missing ENDCHAR",
+	)
+}
+
+// parseBDFBox parses bounding box in form of `BBX <width> <height> <x>
+// <y>`, where x and y are offset of bottom left corner relative to origin.
+// Returned rectangle is relative to origin with y axis pointing down.
+func parseBDFBox(fields []string) (image.Rectangle, error) {
+	if len(fields) < 5 {
+		return image.ZR, karma.Format(
+			ErrMalformedFont,
+			"bounding box should have 4 values",
+		)
+	}
+
+	values := make([]int, 4)
+
+	for i := range values {
+		var err error
+
+		values[i], err = strconv.Atoi(fields[i+1])
+		if err != nil {
+			return image.ZR, err
+		}
+	}
+
+	var (
+		width  = values[0]
+		height = values[1]
+		x      = values[2]
+		y      = values[3]
+	)
+
+	// Glyphs without ink, like space, have empty box, but box can't be
+	// turned inside out.
+	if width < 0 || height < 0 {
+		return image.ZR, karma.Format(
+			ErrMalformedFont,
+			"bounding box should have non-negative size",
+		)
+	}
+
+	return image.Rect(x, -(y + height), x+width, -y), nil
+}
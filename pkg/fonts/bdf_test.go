@@ -0,0 +1,207 @@
+package fonts
+
+import (
+	"strings"
+	"testing"
+)
+
+const testBDF = `STARTFONT 2.1
+FONT -misc-test-medium-r-normal--8-80-72-72-c-40-iso10646-1
+SIZE 8 72 72
+FONTBOUNDINGBOX 4 8 0 -2
+STARTPROPERTIES 2
+FONT_ASCENT 6
+FONT_DESCENT 2
+ENDPROPERTIES
+CHARS 2
+STARTCHAR A
+ENCODING 65
+DWIDTH 4 0
+BBX 4 8 0 -2
+BITMAP
+60
+90
+90
+F0
+90
+90
+00
+00
+ENDCHAR
+STARTCHAR B
+ENCODING 66
+DWIDTH 4 0
+BBX 4 8 0 -2
+BITMAP
+E0
+90
+E0
+90
+90
+E0
+00
+00
+ENDCHAR
+ENDFONT
+`
+
+// testGlyphA is mask of glyph A in test fonts, one string per row.
+var testGlyphA = []string{
+	".##.",
+	"#..#",
+	"#..#",
+	"####",
+	"#..#",
+	"#..#",
+	"....",
+	"....",
+}
+
+func TestParseBDF(t *testing.T) {
+	face, err := parseBDF([]byte(testBDF), rasterOptions{dpi: 72, size: 8})
+	if err != nil {
+		t.Fatalf("unable to parse font: %s", err)
+	}
+
+	assertBitmap(t, face, "AB", "C")
+}
+
+func TestParseBDF_Malformed(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"empty", ""},
+		{"no glyphs", testBDF[:strings.Index(testBDF, "STARTCHAR")]},
+		{"missing ENDCHAR", testBDF[:strings.Index(testBDF, "ENDCHAR")]},
+		{"invalid box", strings.Replace(testBDF, "BBX 4 8 0 -2", "BBX 4 8", 1)},
+		{"negative width", strings.Replace(testBDF, "BBX 4 8 0 -2", "BBX -4 8 0 -2", 1)},
+		{"negative height", strings.Replace(testBDF, "BBX 4 8 0 -2", "BBX 4 -8 0 -2", 1)},
+		{"missing box", strings.Replace(testBDF, "BBX 4 8 0 -2\n", "", 1)},
+		{
+			"bitmap before box",
+			strings.Replace(
+				testBDF,
+				"BBX 4 8 0 -2\nBITMAP\n",
+				"BITMAP\nBBX 4 8 0 -2\n",
+				1,
+			),
+		},
+		{"invalid bitmap", strings.Replace(testBDF, "F0", "XX", 1)},
+		{"invalid ascent", strings.Replace(testBDF, "FONT_ASCENT 6", "FONT_ASCENT x", 1)},
+	}
+
+	for _, test := range tests {
+		_, err := parseBDF([]byte(test.body), rasterOptions{dpi: 72, size: 8})
+		if err == nil {
+			t.Errorf("%s: expected error", test.name)
+		}
+	}
+}
+
+func TestParseBDF_EmptyGlyph(t *testing.T) {
+	body := strings.Replace(
+		testBDF,
+		"ENDFONT",
+		"STARTCHAR space\nENCODING 32\nDWIDTH 4 0\nBBX 0 0 0 0\n"+
+			"BITMAP\nENDCHAR\nENDFONT",
+		1,
+	)
+
+	face, err := parseBDF([]byte(body), rasterOptions{dpi: 72, size: 8})
+	if err != nil {
+		t.Fatalf("unable to parse font: %s", err)
+	}
+
+	assertBitmap(t, face, "AB ", "C")
+}
+
+func TestParseBDF_Scale(t *testing.T) {
+	face, err := parseBDF([]byte(testBDF), rasterOptions{dpi: 144, size: 8})
+	if err != nil {
+		t.Fatalf("unable to parse font: %s", err)
+	}
+
+	if face.scale != 2 {
+		t.Fatalf("expected scale 2, got %d", face.scale)
+	}
+
+	if advance := face.getAdvance('A'); advance != 8 {
+		t.Errorf("expected advance 8, got %d", advance)
+	}
+
+	mask, offset, err := face.getMask('A')
+	if err != nil {
+		t.Fatalf("unable to get mask: %s", err)
+	}
+
+	if mask.Rect.Dx() != 8 || mask.Rect.Dy() != 16 {
+		t.Errorf("expected 8x16 mask, got %v", mask.Rect)
+	}
+
+	if offset.X != 0 || offset.Y != -12 {
+		t.Errorf("expected offset (0,-12), got %v", offset)
+	}
+}
+
+// assertBitmap checks that face has metrics of test font, glyphs for every
+// char of present and no glyphs for chars of missing.
+func assertBitmap(t *testing.T, face *bitmap, present string, missing string) {
+	t.Helper()
+
+	if face.ascent != 6 || face.descent != 2 {
+		t.Errorf(
+			"expected ascent 6 and descent 2, got %d and %d",
+			face.ascent,
+			face.descent,
+		)
+	}
+
+	for _, char := range present {
+		if !face.has(char) {
+			t.Errorf("glyph %q is missing", char)
+		}
+
+		if advance := face.getAdvance(char); advance != 4 {
+			t.Errorf("glyph %q: expected advance 4, got %d", char, advance)
+		}
+	}
+
+	for _, char := range missing {
+		if face.has(char) {
+			t.Errorf("glyph %q is unexpected", char)
+		}
+	}
+
+	if !strings.ContainsRune(present, 'A') {
+		return
+	}
+
+	mask, offset, err := face.getMask('A')
+	if err != nil {
+		t.Fatalf("unable to get mask: %s", err)
+	}
+
+	if offset.X != 0 || offset.Y != -6 {
+		t.Errorf("expected offset (0,-6), got %v", offset)
+	}
+
+	for y, row := range testGlyphA {
+		for x, pixel := range row {
+			expected := uint8(0)
+			if pixel == '#' {
+				expected = 0xff
+			}
+
+			if actual := mask.AlphaAt(x, y).A; actual != expected {
+				t.Errorf(
+					"glyph 'A': pixel (%d,%d) expected %#x, got %#x",
+					x,
+					y,
+					expected,
+					actual,
+				)
+			}
+		}
+	}
+}
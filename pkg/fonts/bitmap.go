@@ -0,0 +1,155 @@
+package fonts
+
+import (
+	"image"
+	"math"
+)
+
+// DefaultBitmapDPI is resolution assumed for bitmap fonts which don't
+// declare one.
+const DefaultBitmapDPI = 72
+
+// bitmap is typeface of bitmap font, like BDF or PCF. Glyphs are drawn
+// as is, using cell metrics declared by font. Bitmap fonts have fixed
+// size, so they are only scaled by integer factor when DPI font is loaded
+// with is several times larger than font resolution.
+type bitmap struct {
+	glyphs map[rune]*bitmapGlyph
+
+	// ascent and descent are declared by font and define cell height.
+	ascent  int
+	descent int
+
+	// resolution is DPI font is designed for.
+	resolution int
+
+	scale int
+}
+
+type bitmapGlyph struct {
+	advance int
+	mask    *image.Alpha
+
+	// offset is position of mask top left corner relative to glyph origin
+	// on baseline.
+	offset image.Point
+}
+
+func newBitmap() *bitmap {
+	return &bitmap{
+		glyphs: make(map[rune]*bitmapGlyph),
+	}
+}
+
+// setScale calculates integer scale factor of font for given DPI.
+func (face *bitmap) setScale(options rasterOptions) {
+	resolution := face.resolution
+	if resolution <= 0 {
+		resolution = DefaultBitmapDPI
+	}
+
+	face.scale = int(math.Round(options.dpi / float64(resolution)))
+	if face.scale < 1 {
+		face.scale = 1
+	}
+}
+
+func (face *bitmap) has(char rune) bool {
+	_, ok := face.glyphs[char]
+
+	return ok
+}
+
+func (face *bitmap) getAdvance(char rune) int {
+	glyph, ok := face.glyphs[char]
+	if !ok {
+		return 0
+	}
+
+	return glyph.advance * face.scale
+}
+
+func (face *bitmap) getMask(char rune) (*image.Alpha, image.Point, error) {
+	glyph, ok := face.glyphs[char]
+	if !ok {
+		return nil, image.ZP, nil
+	}
+
+	if face.scale == 1 {
+		return glyph.mask, glyph.offset, nil
+	}
+
+	var (
+		bounds = glyph.mask.Bounds()
+		mask   = image.NewAlpha(
+			image.Rect(
+				0,
+				0,
+				bounds.Dx()*face.scale,
+				bounds.Dy()*face.scale,
+			),
+		)
+	)
+
+	for y := 0; y < mask.Rect.Dy(); y++ {
+		for x := 0; x < mask.Rect.Dx(); x++ {
+			mask.SetAlpha(
+				x,
+				y,
+				glyph.mask.AlphaAt(x/face.scale, y/face.scale),
+			)
+		}
+	}
+
+	return mask, glyph.offset.Mul(face.scale), nil
+}
+
+// getMetrics returns cell metrics of font: cell width is advance width of
+// most glyphs and cell height is sum of ascent and descent declared by
+// font.
+func (face *bitmap) getMetrics() metrics {
+	widths := map[int]int{}
+
+	for _, glyph := range face.glyphs {
+		widths[glyph.advance]++
+	}
+
+	return metrics{
+		length: len(face.glyphs),
+		width:  getMostCommon(widths) * face.scale,
+		height: (face.ascent + face.descent) * face.scale,
+
+		descender: -face.descent * face.scale,
+	}
+}
+
+// add adds glyph with given bits, which are rows of glyph image padded to
+// `stride` bytes with most significant bit being leftmost pixel.
+func (face *bitmap) add(
+	char rune,
+	advance int,
+	bounds image.Rectangle,
+	bits []byte,
+	stride int,
+) {
+	mask := image.NewAlpha(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			index := y*stride + x/8
+			if index >= len(bits) {
+				break
+			}
+
+			if bits[index]&(0x80>>uint(x%8)) != 0 {
+				mask.Pix[y*mask.Stride+x] = 0xff
+			}
+		}
+	}
+
+	face.glyphs[char] = &bitmapGlyph{
+		advance: advance,
+		mask:    mask,
+		offset:  bounds.Min,
+	}
+}
@@ -0,0 +1,660 @@
+package fonts
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// FontBuiltinGlyphs enables procedural drawing of box-drawing, block
+// element and braille chars, which is enabled by default. Procedural
+// glyphs fill cell exactly, so lines of adjacent cells join without gaps
+// regardless of font outlines.
+type FontBuiltinGlyphs bool
+
+const (
+	boxUp = iota
+	boxRight
+	boxDown
+	boxLeft
+)
+
+const (
+	boxNone = iota
+	boxLight
+	boxHeavy
+	boxDouble
+)
+
+// boxLines describes lines of box-drawing chars as weights of lines going
+// from cell center up, right, down and left: 0 is no line, 1 is light line,
+// 2 is heavy line and 3 is double line.
+var boxLines = map[rune][4]uint8{
+	0x2500: {0, 1, 0, 1}, 0x2501: {0, 2, 0, 2},
+	0x2502: {1, 0, 1, 0}, 0x2503: {2, 0, 2, 0},
+
+	0x250C: {0, 1, 1, 0}, 0x250D: {0, 2, 1, 0},
+	0x250E: {0, 1, 2, 0}, 0x250F: {0, 2, 2, 0},
+	0x2510: {0, 0, 1, 1}, 0x2511: {0, 0, 1, 2},
+	0x2512: {0, 0, 2, 1}, 0x2513: {0, 0, 2, 2},
+	0x2514: {1, 1, 0, 0}, 0x2515: {1, 2, 0, 0},
+	0x2516: {2, 1, 0, 0}, 0x2517: {2, 2, 0, 0},
+	0x2518: {1, 0, 0, 1}, 0x2519: {1, 0, 0, 2},
+	0x251A: {2, 0, 0, 1}, 0x251B: {2, 0, 0, 2},
+
+	0x251C: {1, 1, 1, 0}, 0x251D: {1, 2, 1, 0},
+	0x251E: {2, 1, 1, 0}, 0x251F: {1, 1, 2, 0},
+	0x2520: {2, 1, 2, 0}, 0x2521: {2, 2, 1, 0},
+	0x2522: {1, 2, 2, 0}, 0x2523: {2, 2, 2, 0},
+	0x2524: {1, 0, 1, 1}, 0x2525: {1, 0, 1, 2},
+	0x2526: {2, 0, 1, 1}, 0x2527: {1, 0, 2, 1},
+	0x2528: {2, 0, 2, 1}, 0x2529: {2, 0, 1, 2},
+	0x252A: {1, 0, 2, 2}, 0x252B: {2, 0, 2, 2},
+
+	0x252C: {0, 1, 1, 1}, 0x252D: {0, 1, 1, 2},
+	0x252E: {0, 2, 1, 1}, 0x252F: {0, 2, 1, 2},
+	0x2530: {0, 1, 2, 1}, 0x2531: {0, 1, 2, 2},
+	0x2532: {0, 2, 2, 1}, 0x2533: {0, 2, 2, 2},
+	0x2534: {1, 1, 0, 1}, 0x2535: {1, 1, 0, 2},
+	0x2536: {1, 2, 0, 1}, 0x2537: {1, 2, 0, 2},
+	0x2538: {2, 1, 0, 1}, 0x2539: {2, 1, 0, 2},
+	0x253A: {2, 2, 0, 1}, 0x253B: {2, 2, 0, 2},
+
+	0x253C: {1, 1, 1, 1}, 0x253D: {1, 1, 1, 2},
+	0x253E: {1, 2, 1, 1}, 0x253F: {1, 2, 1, 2},
+	0x2540: {2, 1, 1, 1}, 0x2541: {1, 1, 2, 1},
+	0x2542: {2, 1, 2, 1}, 0x2543: {2, 1, 1, 2},
+	0x2544: {2, 2, 1, 1}, 0x2545: {1, 1, 2, 2},
+	0x2546: {1, 2, 2, 1}, 0x2547: {2, 2, 1, 2},
+	0x2548: {1, 2, 2, 2}, 0x2549: {2, 1, 2, 2},
+	0x254A: {2, 2, 2, 1}, 0x254B: {2, 2, 2, 2},
+
+	0x2550: {0, 3, 0, 3}, 0x2551: {3, 0, 3, 0},
+	0x2552: {0, 3, 1, 0}, 0x2553: {0, 1, 3, 0},
+	0x2554: {0, 3, 3, 0}, 0x2555: {0, 0, 1, 3},
+	0x2556: {0, 0, 3, 1}, 0x2557: {0, 0, 3, 3},
+	0x2558: {1, 3, 0, 0}, 0x2559: {3, 1, 0, 0},
+	0x255A: {3, 3, 0, 0}, 0x255B: {1, 0, 0, 3},
+	0x255C: {3, 0, 0, 1}, 0x255D: {3, 0, 0, 3},
+	0x255E: {1, 3, 1, 0}, 0x255F: {3, 1, 3, 0},
+	0x2560: {3, 3, 3, 0}, 0x2561: {1, 0, 1, 3},
+	0x2562: {3, 0, 3, 1}, 0x2563: {3, 0, 3, 3},
+	0x2564: {0, 3, 1, 3}, 0x2565: {0, 1, 3, 1},
+	0x2566: {0, 3, 3, 3}, 0x2567: {1, 3, 0, 3},
+	0x2568: {3, 1, 0, 1}, 0x2569: {3, 3, 0, 3},
+	0x256A: {1, 3, 1, 3}, 0x256B: {3, 1, 3, 1},
+	0x256C: {3, 3, 3, 3},
+
+	0x2574: {0, 0, 0, 1}, 0x2575: {1, 0, 0, 0},
+	0x2576: {0, 1, 0, 0}, 0x2577: {0, 0, 1, 0},
+	0x2578: {0, 0, 0, 2}, 0x2579: {2, 0, 0, 0},
+	0x257A: {0, 2, 0, 0}, 0x257B: {0, 0, 2, 0},
+	0x257C: {0, 2, 0, 1}, 0x257D: {1, 0, 2, 0},
+	0x257E: {0, 1, 0, 2}, 0x257F: {2, 0, 1, 0},
+}
+
+// boxDashes describes dashed lines as amount of dashes, weight of line and
+// whether line is vertical.
+var boxDashes = map[rune]struct {
+	count    int
+	weight   uint8
+	vertical bool
+}{
+	0x2504: {3, boxLight, false}, 0x2505: {3, boxHeavy, false},
+	0x2506: {3, boxLight, true}, 0x2507: {3, boxHeavy, true},
+	0x2508: {4, boxLight, false}, 0x2509: {4, boxHeavy, false},
+	0x250A: {4, boxLight, true}, 0x250B: {4, boxHeavy, true},
+	0x254C: {2, boxLight, false}, 0x254D: {2, boxHeavy, false},
+	0x254E: {2, boxLight, true}, 0x254F: {2, boxHeavy, true},
+}
+
+// isBuiltin reports whether char is drawn procedurally.
+func isBuiltin(char rune) bool {
+	switch {
+	case char >= 0x2500 && char <= 0x259F:
+		return true
+	case char >= 0x2800 && char <= 0x28FF:
+		return true
+	default:
+		return false
+	}
+}
+
+// getBuiltinGlyph returns procedurally drawn glyph for given char or nil
+// if char is not drawn procedurally. Builtin glyphs are the same for
+// every style.
+func (font *Font) getBuiltinGlyph(char string) *Glyph {
+	if !font.builtin.enabled {
+		return nil
+	}
+
+	chars := []rune(char)
+	if len(chars) != 1 || !isBuiltin(chars[0]) {
+		return nil
+	}
+
+	if glyph, ok := font.builtin.glyphs[chars[0]]; ok {
+		return glyph
+	}
+
+	row, column := font.allocate(1)
+
+	cell := canvas{
+		image: font.Image,
+		rect: image.Rect(
+			column*font.metrics.width,
+			row*font.metrics.height,
+			(column+1)*font.metrics.width,
+			(row+1)*font.metrics.height,
+		),
+	}
+
+	cell.drawBuiltin(chars[0])
+
+	font.atlas.changed(cell.rect)
+
+	glyph := &Glyph{
+		Row:    row,
+		Column: column,
+		Char:   char,
+	}
+
+	font.atlas.add(glyph)
+
+	font.builtin.glyphs[chars[0]] = glyph
+
+	font.cache.dirty = true
+
+	return glyph
+}
+
+// canvas is atlas cell procedural glyph is drawn into. All coordinates are
+// relative to top left corner of the cell.
+type canvas struct {
+	image *image.RGBA
+	rect  image.Rectangle
+}
+
+func (cell canvas) drawBuiltin(char rune) {
+	switch {
+	case char >= 0x2800:
+		cell.drawBraille(char)
+
+	case char >= 0x2580:
+		cell.drawBlock(char)
+
+	case char >= 0x256D && char <= 0x2570:
+		cell.drawArc(char)
+
+	case char >= 0x2571 && char <= 0x2573:
+		cell.drawDiagonal(char)
+
+	default:
+		if dash, ok := boxDashes[char]; ok {
+			cell.drawDash(dash.count, dash.weight, dash.vertical)
+		}
+
+		if lines, ok := boxLines[char]; ok {
+			cell.drawLines(lines)
+		}
+	}
+}
+
+func (cell canvas) getThickness(weight uint8) int {
+	light := int(math.Round(float64(cell.rect.Dx()) / 8))
+	if light < 1 {
+		light = 1
+	}
+
+	if weight == boxHeavy {
+		return light * 2
+	}
+
+	return light
+}
+
+// fill fills given rectangle with specified opacity. Rectangle is clipped
+// by cell bounds.
+func (cell canvas) fill(x0, y0, x1, y1 int, alpha uint8) {
+	area := image.Rect(x0, y0, x1, y1).
+		Add(cell.rect.Min).
+		Intersect(cell.rect)
+
+	for y := area.Min.Y; y < area.Max.Y; y++ {
+		for x := area.Min.X; x < area.Max.X; x++ {
+			cell.plot(x, y, alpha)
+		}
+	}
+}
+
+// plot sets opacity of pixel in atlas, keeping more opaque value.
+func (cell canvas) plot(x, y int, alpha uint8) {
+	if cell.image.RGBAAt(x, y).A < alpha {
+		cell.image.SetRGBA(x, y, color.RGBA{0, 0, 0, alpha})
+	}
+}
+
+// shade fills pixels covered by given shape, where coverage is estimated
+// by sampling every pixel several times.
+func (cell canvas) shade(inside func(x, y float64) bool) {
+	const samples = 4
+
+	for y := 0; y < cell.rect.Dy(); y++ {
+		for x := 0; x < cell.rect.Dx(); x++ {
+			covered := 0
+
+			for sy := 0; sy < samples; sy++ {
+				for sx := 0; sx < samples; sx++ {
+					if inside(
+						float64(x)+(float64(sx)+0.5)/samples,
+						float64(y)+(float64(sy)+0.5)/samples,
+					) {
+						covered++
+					}
+				}
+			}
+
+			if covered > 0 {
+				cell.plot(
+					cell.rect.Min.X+x,
+					cell.rect.Min.Y+y,
+					uint8(covered*0xff/(samples*samples)),
+				)
+			}
+		}
+	}
+}
+
+// span returns extent of line with given thickness centered at given
+// coordinate.
+func span(center int, thickness int) (int, int) {
+	from := center - thickness/2
+
+	return from, from + thickness
+}
+
+// drawLines draws box-drawing char consisting of horizontal and vertical
+// lines. Lines are drawn as arms going from cell edges to the center and
+// every arm is extended or shortened to join perpendicular lines
+// seamlessly, leaving gaps between double lines.
+func (cell canvas) drawLines(lines [4]uint8) {
+	cell.drawArms(
+		lines[boxLeft], lines[boxRight],
+		lines[boxUp], lines[boxDown],
+		false,
+	)
+
+	cell.drawArms(
+		lines[boxUp], lines[boxDown],
+		lines[boxLeft], lines[boxRight],
+		true,
+	)
+}
+
+// drawArms draws two opposite arms of box-drawing char, which are
+// horizontal or vertical. Coordinates are calculated along arms and
+// across them, and are transposed for vertical arms.
+func (cell canvas) drawArms(
+	before uint8,
+	after uint8,
+	above uint8,
+	below uint8,
+	vertical bool,
+) {
+	var (
+		along  = cell.rect.Dx()
+		across = cell.rect.Dy()
+	)
+
+	if vertical {
+		along, across = across, along
+	}
+
+	var (
+		center = along / 2
+		middle = across / 2
+
+		light = cell.getThickness(boxLight)
+		gap   = light
+	)
+
+	fill := func(from, to, top, bottom int) {
+		if vertical {
+			cell.fill(top, from, bottom, to, 0xff)
+		} else {
+			cell.fill(from, top, to, bottom, 0xff)
+		}
+	}
+
+	// edge returns coordinate where arm coming from given side should
+	// end to join perpendicular line of given weight: on far or near
+	// side of perpendicular line.
+	edge := func(weight uint8, side int, far bool) int {
+		line, thickness := center, cell.getThickness(weight)
+
+		if weight == boxDouble {
+			if far {
+				line = center - side*gap
+			} else {
+				line = center + side*gap
+			}
+		}
+
+		from, to := span(line, thickness)
+		if side < 0 {
+			return to
+		}
+
+		return from
+	}
+
+	for _, arm := range []struct {
+		weight   uint8
+		opposite uint8
+		side     int
+	}{
+		{before, after, -1},
+		{after, before, 1},
+	} {
+		if arm.weight == boxNone {
+			continue
+		}
+
+		draw := func(end int, top int, bottom int) {
+			if arm.side < 0 {
+				fill(0, end, top, bottom)
+			} else {
+				fill(end, along, top, bottom)
+			}
+		}
+
+		if arm.weight != boxDouble {
+			end := center
+
+			switch {
+			case above == boxDouble && below == boxDouble &&
+				arm.opposite == boxNone:
+				end = edge(boxDouble, arm.side, false)
+
+			case above != boxNone || below != boxNone:
+				for _, weight := range []uint8{above, below} {
+					if weight == boxNone {
+						continue
+					}
+
+					reach := edge(weight, arm.side, true)
+					if (arm.side < 0) == (reach > end) {
+						end = reach
+					}
+				}
+			}
+
+			top, bottom := span(middle, cell.getThickness(arm.weight))
+
+			draw(end, top, bottom)
+
+			continue
+		}
+
+		for _, line := range []struct {
+			offset int
+			near   uint8
+			far    uint8
+		}{
+			{-gap, above, below},
+			{gap, below, above},
+		} {
+			var end int
+
+			switch {
+			case line.near == boxDouble:
+				end = edge(boxDouble, arm.side, false)
+			case line.near != boxNone:
+				end = edge(line.near, arm.side, true)
+			case line.far != boxNone:
+				end = edge(line.far, arm.side, true)
+			default:
+				end = center
+			}
+
+			top, bottom := span(middle+line.offset, light)
+
+			draw(end, top, bottom)
+		}
+	}
+}
+
+// drawDash draws line split into given amount of dashes.
+func (cell canvas) drawDash(count int, weight uint8, vertical bool) {
+	var (
+		along  = cell.rect.Dx()
+		across = cell.rect.Dy()
+	)
+
+	if vertical {
+		along, across = across, along
+	}
+
+	top, bottom := span(across/2, cell.getThickness(weight))
+
+	for i := 0; i < count; i++ {
+		var (
+			from = i * along / count
+			to   = (i + 1) * along / count
+		)
+
+		gap := (to - from) / 3
+		if gap < 1 {
+			gap = 1
+		}
+
+		if vertical {
+			cell.fill(top, from, bottom, to-gap, 0xff)
+		} else {
+			cell.fill(from, top, to-gap, bottom, 0xff)
+		}
+	}
+}
+
+// drawArc draws rounded corner, which joins light lines going from
+// centers of two adjacent cell edges.
+func (cell canvas) drawArc(char rune) {
+	var (
+		width  = cell.rect.Dx()
+		height = cell.rect.Dy()
+
+		thickness = cell.getThickness(boxLight)
+
+		left, _ = span(width/2, thickness)
+		top, _  = span(height/2, thickness)
+
+		// Arc passes through centers of vertical and horizontal lines.
+		x = float64(left) + float64(thickness)/2
+		y = float64(top) + float64(thickness)/2
+	)
+
+	// Direction from cell center to the arc center.
+	var dx, dy float64
+
+	switch char {
+	case 0x256D:
+		dx, dy = 1, 1
+	case 0x256E:
+		dx, dy = -1, 1
+	case 0x256F:
+		dx, dy = -1, -1
+	case 0x2570:
+		dx, dy = 1, -1
+	}
+
+	// Arc should fit between line centers and cell edges it goes to.
+	radius := math.Min(x, y)
+
+	if dx > 0 {
+		radius = math.Min(radius, float64(width)-x)
+	}
+
+	if dy > 0 {
+		radius = math.Min(radius, float64(height)-y)
+	}
+
+	var (
+		cx = x + dx*radius
+		cy = y + dy*radius
+	)
+
+	cell.shade(func(px, py float64) bool {
+		// Straight parts of lines between arc and cell edges.
+		if (px-cx)*dx >= 0 && math.Abs(py-y) <= float64(thickness)/2 {
+			return true
+		}
+
+		if (py-cy)*dy >= 0 && math.Abs(px-x) <= float64(thickness)/2 {
+			return true
+		}
+
+		if (px-cx)*dx > 0 || (py-cy)*dy > 0 {
+			return false
+		}
+
+		distance := math.Hypot(px-cx, py-cy)
+
+		return math.Abs(distance-radius) <= float64(thickness)/2
+	})
+}
+
+// drawDiagonal draws diagonal lines going through cell corners.
+func (cell canvas) drawDiagonal(char rune) {
+	var (
+		width  = float64(cell.rect.Dx())
+		height = float64(cell.rect.Dy())
+		length = math.Hypot(width, height)
+
+		thickness = float64(cell.getThickness(boxLight))
+	)
+
+	cell.shade(func(x, y float64) bool {
+		var (
+			rising  = math.Abs(height*x+width*y-width*height) / length
+			falling = math.Abs(height*x-width*y) / length
+		)
+
+		switch char {
+		case 0x2571:
+			return rising <= thickness/2
+		case 0x2572:
+			return falling <= thickness/2
+		default:
+			return rising <= thickness/2 || falling <= thickness/2
+		}
+	})
+}
+
+// drawBlock draws block elements: eighths of cell, quadrants and shades.
+func (cell canvas) drawBlock(char rune) {
+	var (
+		width  = cell.rect.Dx()
+		height = cell.rect.Dy()
+	)
+
+	eighth := func(size int, amount int) int {
+		return int(math.Round(float64(size*amount) / 8))
+	}
+
+	switch {
+	case char == 0x2580:
+		cell.fill(0, 0, width, height/2, 0xff)
+
+	case char >= 0x2581 && char <= 0x2588:
+		size := eighth(height, int(char-0x2580))
+
+		cell.fill(0, height-size, width, height, 0xff)
+
+	case char >= 0x2589 && char <= 0x258F:
+		size := eighth(width, int(0x2590-char))
+
+		cell.fill(0, 0, size, height, 0xff)
+
+	case char == 0x2590:
+		cell.fill(width/2, 0, width, height, 0xff)
+
+	case char >= 0x2591 && char <= 0x2593:
+		cell.fill(0, 0, width, height, uint8(int(char-0x2590)*0x40))
+
+	case char == 0x2594:
+		cell.fill(0, 0, width, eighth(height, 1), 0xff)
+
+	case char == 0x2595:
+		cell.fill(width-eighth(width, 1), 0, width, height, 0xff)
+
+	default:
+		// Quadrants are encoded as bits: upper left, upper right, lower
+		// left and lower right.
+		quadrants := map[rune]int{
+			0x2596: 4, 0x2597: 8, 0x2598: 1, 0x2599: 1 | 4 | 8,
+			0x259A: 1 | 8, 0x259B: 1 | 2 | 4, 0x259C: 1 | 2 | 8,
+			0x259D: 2, 0x259E: 2 | 4, 0x259F: 2 | 4 | 8,
+		}[char]
+
+		for i := 0; i < 4; i++ {
+			if quadrants&(1<<uint(i)) == 0 {
+				continue
+			}
+
+			var (
+				x = i % 2 * width / 2
+				y = i / 2 * height / 2
+			)
+
+			cell.fill(
+				x,
+				y,
+				x+width/2+i%2*(width%2),
+				y+height/2+i/2*(height%2),
+				0xff,
+			)
+		}
+	}
+}
+
+// drawBraille draws braille pattern of 2x4 dots, where every bit of char
+// offset from U+2800 raises one dot.
+func (cell canvas) drawBraille(char rune) {
+	// Positions of dots for every bit, as column and row.
+	dots := [8][2]int{
+		{0, 0}, {0, 1}, {0, 2}, {1, 0},
+		{1, 1}, {1, 2}, {0, 3}, {1, 3},
+	}
+
+	var (
+		width  = cell.rect.Dx() / 2
+		height = cell.rect.Dy() / 4
+		size   = width / 2
+	)
+
+	if height/2 < size {
+		size = height / 2
+	}
+
+	if size < 1 {
+		size = 1
+	}
+
+	var (
+		left = (cell.rect.Dx() - width*2) / 2
+		top  = (cell.rect.Dy() - height*4) / 2
+	)
+
+	for bit, dot := range dots {
+		if (char-0x2800)&(1<<uint(bit)) == 0 {
+			continue
+		}
+
+		var (
+			x = left + dot[0]*width + (width-size)/2
+			y = top + dot[1]*height + (height-size)/2
+		)
+
+		cell.fill(x, y, x+size, y+size, 0xff)
+	}
+}
@@ -0,0 +1,500 @@
+package fonts
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/reconquest/karma-go"
+)
+
+// cacheVersion should be incremented every time format of cache or the way
+// glyphs are rasterized is changed, so stale caches are ignored.
+const cacheVersion = 3
+
+// FontCacheDir specifies directory where rasterized atlas, glyph map and
+// font metrics are stored between restarts. Cache is not used if
+// directory is not specified.
+type FontCacheDir string
+
+// DefaultCacheDir returns cache directory under XDG_CACHE_HOME, which is
+// `~/.cache` by default.
+func DefaultCacheDir() string {
+	dir := os.Getenv("XDG_CACHE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+
+		dir = filepath.Join(home, ".cache")
+	}
+
+	return filepath.Join(dir, "mainframe", "fonts")
+}
+
+// cachedAtlas is state of font atlas stored on disk. Glyphs are stored
+// once and referred by index from every map, negative index stands for
+// char which is missing in face.
+type cachedAtlas struct {
+	Version int
+
+	Length    int
+	Width     int
+	Height    int
+	Descender int
+	Left      int
+	Advance   int
+
+	Image *image.RGBA
+
+	Row    int
+	Column int
+
+	Blank       image.Point
+	Replacement int
+
+	Glyphs    []Glyph
+	Faces     map[Style]map[string]int
+	Fallbacks []map[string]int
+	Clusters  []cachedCluster
+	Builtin   map[rune]int
+}
+
+type cachedCluster struct {
+	Text  string
+	Style Style
+	Glyph int
+}
+
+// getCacheKey returns hash of every font file and all options which affect
+// rasterization.
+func getCacheKey(
+	paths map[Style]string,
+	fallbacks []string,
+	options ...interface{},
+) (string, error) {
+	hash := sha256.New()
+
+	fmt.Fprintln(hash, cacheVersion)
+
+	files := []string{}
+
+	for _, style := range styles {
+		files = append(files, paths[style])
+	}
+
+	files = append(files, fallbacks...)
+
+	for _, path := range files {
+		if path == "" {
+			fmt.Fprintln(hash, "-")
+
+			continue
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			return "", karma.
+				Describe("path", path).
+				Format(
+					err,
+					"unable to open font file",
+				)
+		}
+
+		_, err = io.Copy(hash, file)
+
+		file.Close()
+
+		if err != nil {
+			return "", karma.
+				Describe("path", path).
+				Format(
+					err,
+					"unable to read font file",
+				)
+		}
+
+		fmt.Fprintln(hash)
+	}
+
+	for _, option := range options {
+		fmt.Fprintf(hash, "%v\n", option)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// restore loads atlas from cache file. Font is left untouched if cache
+// can't be loaded.
+func (font *Font) restore() error {
+	file, err := os.Open(font.cache.path)
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to decompress font cache",
+		)
+	}
+
+	var cache cachedAtlas
+
+	err = gob.NewDecoder(reader).Decode(&cache)
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to decode font cache",
+		)
+	}
+
+	if !cache.isValid() {
+		return karma.Format(
+			ErrMalformedFont,
+			"font cache is outdated or corrupted",
+		)
+	}
+
+	glyphs := make([]*Glyph, len(cache.Glyphs))
+	for i := range cache.Glyphs {
+		glyph := cache.Glyphs[i]
+
+		glyphs[i] = &glyph
+	}
+
+	get := func(index int) *Glyph {
+		if index < 0 {
+			return nil
+		}
+
+		return glyphs[index]
+	}
+
+	restore := func(indices map[string]int, target map[string]*Glyph) {
+		for char, index := range indices {
+			target[char] = get(index)
+		}
+	}
+
+	for style, indices := range cache.Faces {
+		if face, ok := font.faces[style]; ok {
+			restore(indices, face.glyphs)
+		}
+	}
+
+	for i, indices := range cache.Fallbacks {
+		if i < len(font.fallbacks) {
+			restore(indices, font.fallbacks[i].glyphs)
+		}
+	}
+
+	for _, cluster := range cache.Clusters {
+		font.clusters[cluster.key()] = get(cluster.Glyph)
+	}
+
+	for char, index := range cache.Builtin {
+		font.builtin.glyphs[char] = get(index)
+	}
+
+	font.Replacement = get(cache.Replacement)
+
+	font.metrics = metrics{
+		length:    cache.Length,
+		width:     cache.Width,
+		height:    cache.Height,
+		descender: cache.Descender,
+		left:      cache.Left,
+		advance:   cache.Advance,
+	}
+
+	font.Image = cache.Image
+
+	font.atlas.columns = AtlasColumns
+	font.atlas.row = cache.Row
+	font.atlas.column = cache.Column
+
+	font.blank.row = cache.Blank.Y
+	font.blank.column = cache.Blank.X
+
+	// Chars without outlines refer to blank cell, which is not registered
+	// in atlas.
+	for _, glyph := range glyphs {
+		if glyph.Row == font.blank.row && glyph.Column == font.blank.column {
+			continue
+		}
+
+		font.atlas.add(glyph)
+	}
+
+	return nil
+}
+
+// isValid checks that cache is written by current version, atlas image is
+// consistent with font metrics, every glyph and atlas position lies within
+// atlas image and every glyph reference points to stored glyph, so
+// corrupted cache is rejected instead of causing panics on use.
+func (cache *cachedAtlas) isValid() bool {
+	if cache.Version != cacheVersion || cache.Image == nil {
+		return false
+	}
+
+	if cache.Width <= 0 || cache.Height <= 0 {
+		return false
+	}
+
+	var (
+		atlasImage = cache.Image
+		rows       = atlasImage.Rect.Dy() / cache.Height
+	)
+
+	if atlasImage.Rect.Min.X != 0 || atlasImage.Rect.Min.Y != 0 ||
+		atlasImage.Rect.Dx() != AtlasColumns*cache.Width ||
+		atlasImage.Rect.Dy() <= 0 ||
+		atlasImage.Stride != 4*atlasImage.Rect.Dx() ||
+		len(atlasImage.Pix) != atlasImage.Stride*atlasImage.Rect.Dy() {
+		return false
+	}
+
+	contains := func(row int, column int, span int) bool {
+		return row >= 0 && row < rows &&
+			column >= 0 && column+span <= AtlasColumns
+	}
+
+	for _, glyph := range cache.Glyphs {
+		span := 1
+		if glyph.Wide {
+			span = 2
+		}
+
+		if !contains(glyph.Row, glyph.Column, span) {
+			return false
+		}
+	}
+
+	if !contains(cache.Blank.Y, cache.Blank.X, 1) ||
+		!contains(cache.Row, cache.Column, 0) {
+		return false
+	}
+
+	valid := func(index int) bool {
+		return index < len(cache.Glyphs)
+	}
+
+	indices := append([]map[string]int{}, cache.Fallbacks...)
+	for _, face := range cache.Faces {
+		indices = append(indices, face)
+	}
+
+	for _, glyphs := range indices {
+		for _, index := range glyphs {
+			if !valid(index) {
+				return false
+			}
+		}
+	}
+
+	for _, cluster := range cache.Clusters {
+		if !valid(cluster.Glyph) {
+			return false
+		}
+	}
+
+	for _, index := range cache.Builtin {
+		if !valid(index) {
+			return false
+		}
+	}
+
+	return cache.Replacement >= 0 && valid(cache.Replacement)
+}
+
+// SaveCache writes atlas into cache directory, so font is loaded without
+// rasterizing glyphs again on next start. Nothing is written if no glyphs
+// were added since font was loaded from cache.
+//
+// Font is locked only while its state is copied, so font can be used while
+// cache is encoded and written.
+func (font *Font) SaveCache() error {
+	font.Lock()
+
+	if font.cache.path == "" || !font.cache.dirty {
+		font.Unlock()
+
+		return nil
+	}
+
+	var (
+		path  = font.cache.path
+		cache = font.getCachedAtlas()
+	)
+
+	font.cache.dirty = false
+
+	font.Unlock()
+
+	err := writeCache(path, cache)
+	if err != nil {
+		font.Lock()
+		font.cache.dirty = true
+		font.Unlock()
+
+		return err
+	}
+
+	return nil
+}
+
+// getCachedAtlas returns copy of font atlas state, which is stored on disk.
+func (font *Font) getCachedAtlas() *cachedAtlas {
+	var (
+		cache = &cachedAtlas{
+			Version: cacheVersion,
+
+			Length:    font.metrics.length,
+			Width:     font.metrics.width,
+			Height:    font.metrics.height,
+			Descender: font.metrics.descender,
+			Left:      font.metrics.left,
+			Advance:   font.metrics.advance,
+
+			Image: image.NewRGBA(font.Image.Rect),
+
+			Row:    font.atlas.row,
+			Column: font.atlas.column,
+
+			Blank: image.Pt(font.blank.column, font.blank.row),
+
+			Faces:   map[Style]map[string]int{},
+			Builtin: map[rune]int{},
+		}
+
+		indices = map[*Glyph]int{}
+	)
+
+	index := func(glyph *Glyph) int {
+		if glyph == nil {
+			return -1
+		}
+
+		if index, ok := indices[glyph]; ok {
+			return index
+		}
+
+		indices[glyph] = len(cache.Glyphs)
+		cache.Glyphs = append(cache.Glyphs, *glyph)
+
+		return indices[glyph]
+	}
+
+	save := func(glyphs map[string]*Glyph) map[string]int {
+		result := map[string]int{}
+
+		for char, glyph := range glyphs {
+			result[char] = index(glyph)
+		}
+
+		return result
+	}
+
+	cache.Replacement = index(font.Replacement)
+
+	for style, face := range font.faces {
+		cache.Faces[style] = save(face.glyphs)
+	}
+
+	for _, fallback := range font.fallbacks {
+		cache.Fallbacks = append(cache.Fallbacks, save(fallback.glyphs))
+	}
+
+	for key, glyph := range font.clusters {
+		cache.Clusters = append(cache.Clusters, cachedCluster{
+			Text:  key.text,
+			Style: key.style,
+			Glyph: index(glyph),
+		})
+	}
+
+	for char, glyph := range font.builtin.glyphs {
+		cache.Builtin[char] = index(glyph)
+	}
+
+	copy(cache.Image.Pix, font.Image.Pix)
+
+	return cache
+}
+
+// writeCache encodes atlas state into cache file at given path.
+func writeCache(path string, cache *cachedAtlas) error {
+	dir := filepath.Dir(path)
+
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return karma.
+			Describe("dir", dir).
+			Format(
+				err,
+				"unable to create font cache dir",
+			)
+	}
+
+	// Cache is written into temporary file first, so concurrently started
+	// instances never read partially written cache.
+	file, err := ioutil.TempFile(dir, filepath.Base(path))
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to create font cache file",
+		)
+	}
+
+	// Atlas is mostly empty, so it compresses well.
+	writer := gzip.NewWriter(file)
+
+	err = gob.NewEncoder(writer).Encode(cache)
+	if err == nil {
+		err = writer.Close()
+	}
+
+	if err == nil {
+		err = file.Close()
+	} else {
+		file.Close()
+	}
+
+	if err == nil {
+		err = os.Rename(file.Name(), path)
+	}
+
+	if err != nil {
+		os.Remove(file.Name())
+
+		return karma.
+			Describe("path", path).
+			Format(
+				err,
+				"unable to write font cache",
+			)
+	}
+
+	return nil
+}
+
+func (cached cachedCluster) key() cluster {
+	return cluster{
+		text:  cached.Text,
+		style: cached.Style,
+	}
+}
@@ -0,0 +1,98 @@
+package fonts
+
+import (
+	"image"
+	"testing"
+)
+
+func newTestCachedAtlas() *cachedAtlas {
+	return &cachedAtlas{
+		Version: cacheVersion,
+
+		Width:  4,
+		Height: 8,
+
+		Image: image.NewRGBA(image.Rect(0, 0, AtlasColumns*4, 8*2)),
+
+		Row:    1,
+		Column: 3,
+
+		Blank:       image.Pt(0, 0),
+		Replacement: 0,
+
+		Glyphs: []Glyph{
+			{Row: 0, Column: 1, Char: "�"},
+			{Row: 1, Column: 1, Char: "A", Wide: true},
+		},
+
+		Faces: map[Style]map[string]int{
+			StyleRegular: {"A": 1, "B": -1},
+		},
+		Builtin: map[rune]int{},
+	}
+}
+
+func TestCachedAtlas_IsValid(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*cachedAtlas)
+		valid  bool
+	}{
+		{"valid", func(*cachedAtlas) {}, true},
+		{"outdated", func(cache *cachedAtlas) {
+			cache.Version = cacheVersion - 1
+		}, false},
+		{"no image", func(cache *cachedAtlas) {
+			cache.Image = nil
+		}, false},
+		{"zero cell height", func(cache *cachedAtlas) {
+			cache.Height = 0
+		}, false},
+		{"image width", func(cache *cachedAtlas) {
+			cache.Width = 5
+		}, false},
+		{"image origin", func(cache *cachedAtlas) {
+			cache.Image.Rect = cache.Image.Rect.Add(image.Pt(0, 1))
+		}, false},
+		{"image stride", func(cache *cachedAtlas) {
+			cache.Image.Stride--
+		}, false},
+		{"truncated pixels", func(cache *cachedAtlas) {
+			cache.Image.Pix = cache.Image.Pix[:len(cache.Image.Pix)-1]
+		}, false},
+		{"glyph row", func(cache *cachedAtlas) {
+			cache.Glyphs[1].Row = 2
+		}, false},
+		{"negative glyph column", func(cache *cachedAtlas) {
+			cache.Glyphs[1].Column = -1
+		}, false},
+		{"wide glyph at last column", func(cache *cachedAtlas) {
+			cache.Glyphs[1].Column = AtlasColumns - 1
+		}, false},
+		{"blank", func(cache *cachedAtlas) {
+			cache.Blank = image.Pt(AtlasColumns, 0)
+		}, false},
+		{"atlas row", func(cache *cachedAtlas) {
+			cache.Row = 2
+		}, false},
+		{"atlas column", func(cache *cachedAtlas) {
+			cache.Column = AtlasColumns + 1
+		}, false},
+		{"glyph index", func(cache *cachedAtlas) {
+			cache.Faces[StyleRegular]["C"] = 2
+		}, false},
+		{"missing replacement", func(cache *cachedAtlas) {
+			cache.Replacement = -1
+		}, false},
+	}
+
+	for _, test := range tests {
+		cache := newTestCachedAtlas()
+
+		test.modify(cache)
+
+		if valid := cache.isValid(); valid != test.valid {
+			t.Errorf("%s: expected %v, got %v", test.name, test.valid, valid)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package fonts
+
+import (
+	"golang.org/x/text/unicode/norm"
+)
+
+type cluster struct {
+	text  string
+	style Style
+}
+
+// getCluster returns glyph for grapheme cluster, which consists of base
+// char followed by combining chars.
+//
+// Precomposed char is used if cluster has one and font defines it,
+// otherwise glyphs of every char in cluster are drawn over each other in
+// new atlas cell.
+func (font *Font) getCluster(text string, style Style) *Glyph {
+	key := cluster{text, style}
+
+	if glyph, ok := font.clusters[key]; ok {
+		return glyph
+	}
+
+	glyph := font.getGlyph(norm.NFC.String(text), style)
+	if glyph == nil {
+		var err error
+
+		// Cluster which can't be composed is drawn as missing char.
+		glyph, err = font.compose(text, style)
+		if err != nil {
+			glyph = nil
+		}
+	}
+
+	font.clusters[key] = glyph
+
+	font.cache.dirty = true
+
+	return glyph
+}
+
+func (font *Font) compose(text string, style Style) (*Glyph, error) {
+	var (
+		chars = []rune(text)
+		base  = font.getFace(chars[0], style)
+	)
+
+	if base == nil {
+		return nil, nil
+	}
+
+	span := font.getSpan(base, chars[0])
+	if span == 0 {
+		return nil, nil
+	}
+
+	row, column := font.allocate(span)
+
+	for _, char := range chars {
+		handle := font.getFace(char, style)
+		if handle == nil {
+			continue
+		}
+
+		_, err := font.rasterChar(handle, row, column, span, char)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	glyph := &Glyph{
+		Row:    row,
+		Column: column,
+		Char:   text,
+		Style:  style,
+		Wide:   span == 2,
+	}
+
+	font.atlas.add(glyph)
+
+	return glyph, nil
+}
+
+// getFace returns first face suitable for given style which has glyph for
+// specified char, falling back to fallback fonts.
+func (font *Font) getFace(char rune, style Style) typeface {
+	for _, style := range style.fallback() {
+		face, ok := font.faces[style]
+		if !ok {
+			continue
+		}
+
+		if face.handle.has(char) {
+			return face.handle
+		}
+	}
+
+	for _, fallback := range font.fallbacks {
+		if fallback.handle.has(char) {
+			return fallback.handle
+		}
+	}
+
+	return nil
+}
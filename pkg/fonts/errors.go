@@ -0,0 +1,10 @@
+package fonts
+
+import (
+	"errors"
+)
+
+var (
+	ErrUnknownFormat = errors.New("unknown font format")
+	ErrMalformedFont = errors.New("malformed font file")
+)
@@ -2,15 +2,13 @@ package fonts
 
 import (
 	"image"
+	"image/color"
 	"image/draw"
-	"io/ioutil"
-	"math"
-
-	"golang.org/x/image/math/fixed"
+	"path/filepath"
+	"sync"
+	"unicode/utf8"
 
 	"github.com/reconquest/karma-go"
-	"github.com/seletskiy/freetype"
-	"github.com/seletskiy/freetype/truetype"
 
 	xfont "golang.org/x/image/font"
 )
@@ -19,43 +17,131 @@ type Glyph struct {
 	Row    int
 	Column int
 	Char   string
+	Style  Style
+
+	// Wide glyphs occupy two adjacent cells in atlas, so right half of
+	// glyph is located at Column+1.
+	Wide bool
 }
 
 type Font struct {
-	Image  *image.RGBA
+	// Font is locked while new glyphs are added to the atlas.
+	sync.Mutex
+
+	// Image is atlas of glyphs, which are rasterized on first use.
+	Image *image.RGBA
+
+	// Glyphs holds glyphs of regular face which are rasterized so far.
 	Glyphs map[string]*Glyph
 
-	handle  *truetype.Font
-	metrics struct {
-		length int
-		width  int
-		height int
+	// Replacement is drawn in place of chars which are missing in font.
+	Replacement *Glyph
+
+	// blank is position of empty atlas cell, which is used for chars
+	// without outlines.
+	blank struct {
+		row    int
+		column int
+	}
+
+	// path, options, size and dpi are used to load same font with
+	// different parameters.
+	path    string
+	options []interface{}
+	size    float64
+	dpi     float64
+
+	// faces holds additional font faces, like bold or italic, which are
+	// rasterized into the same atlas as regular face.
+	faces map[Style]*face
+
+	// fallbacks are fonts which are used for chars missing in regular
+	// face, in order of priority.
+	fallbacks []*face
+
+	// clusters caches glyphs composed from grapheme clusters.
+	clusters map[cluster]*Glyph
+
+	// builtin holds procedurally drawn glyphs, see FontBuiltinGlyphs.
+	builtin struct {
+		enabled bool
+		glyphs  map[rune]*Glyph
+	}
+
+	// cache is file atlas is stored in, see FontCacheDir. Atlas is saved
+	// only if new glyphs were added since it was loaded.
+	cache struct {
+		path  string
+		dirty bool
+	}
 
-		descender int
+	// spacing enlarges cells of the grid, see FontLineHeight and
+	// FontLetterSpacing.
+	spacing struct {
+		line   int
+		letter int
 	}
+
+	atlas atlas
+
+	// metrics are cell metrics of regular face, which are used for all
+	// faces.
+	metrics metrics
+}
+
+type face struct {
+	handle typeface
+	glyphs map[string]*Glyph
 }
 
 type FontDPI float64
 type FontSize float64
 type FontHinting bool
 
+// FontBold, FontItalic and FontBoldItalic specify paths to font files with
+// corresponding faces.
+type FontBold string
+type FontItalic string
+type FontBoldItalic string
+
+// FontLineHeight specifies height of the cell in percents of line height
+// of the font. Glyphs are centered vertically in the cell.
+type FontLineHeight int
+
+// FontLetterSpacing specifies amount of pixels which is added to width of
+// the cell. Glyphs are centered horizontally in the cell.
+type FontLetterSpacing int
+
+// FontFallback specifies path to font file which is used for chars missing
+// in main font. Several fallback fonts can be specified.
+type FontFallback string
+
 func Load(name string, opts ...interface{}) (*Font, error) {
 	font := &Font{
-		Glyphs: make(map[string]*Glyph),
-	}
+		Glyphs:   make(map[string]*Glyph),
+		faces:    make(map[Style]*face),
+		clusters: make(map[cluster]*Glyph),
 
-	err := font.load(name)
-	if err != nil {
-		return nil, karma.Format(
-			err,
-			"unable to load font file",
-		)
+		path:    name,
+		options: opts,
 	}
 
+	font.builtin.enabled = true
+	font.spacing.line = 100
+	font.builtin.glyphs = make(map[rune]*Glyph)
+
 	var (
 		dpi     float64
 		size    float64
 		hinting xfont.Hinting
+		format  FontFormat
+		cache   FontCacheDir
+
+		paths = map[Style]string{
+			StyleRegular: name,
+		}
+
+		fallbacks = []string{}
 	)
 
 	for _, opt := range opts {
@@ -68,20 +154,171 @@ func Load(name string, opts ...interface{}) (*Font, error) {
 			if opt {
 				hinting = xfont.HintingFull
 			}
+		case FontBold:
+			paths[StyleBold] = string(opt)
+		case FontItalic:
+			paths[StyleItalic] = string(opt)
+		case FontBoldItalic:
+			paths[StyleBoldItalic] = string(opt)
+		case FontFallback:
+			fallbacks = append(fallbacks, string(opt))
+		case FontFormat:
+			format = opt
+		case FontBuiltinGlyphs:
+			font.builtin.enabled = bool(opt)
+		case FontCacheDir:
+			cache = opt
+		case FontLineHeight:
+			font.spacing.line = int(opt)
+		case FontLetterSpacing:
+			font.spacing.letter = int(opt)
 		}
 	}
 
-	err = font.raster(dpi, size, hinting)
-	if err != nil {
-		return nil, karma.Format(
-			err,
-			"unable to rasterize font",
+	options := rasterOptions{
+		dpi:     dpi,
+		size:    size,
+		hinting: hinting,
+	}
+
+	for style, path := range paths {
+		if path == "" {
+			continue
+		}
+
+		// Format is specified for main font file only, so styled font
+		// files can be of other format.
+		detect := FormatAuto
+		if style == StyleRegular {
+			detect = format
+		}
+
+		handle, err := load(path, detect, options)
+		if err != nil {
+			return nil, karma.
+				Describe("path", path).
+				Format(
+					err,
+					"unable to load %s font file",
+					style,
+				)
+		}
+
+		font.faces[style] = &face{
+			handle: handle,
+			glyphs: make(map[string]*Glyph),
+		}
+	}
+
+	for _, path := range fallbacks {
+		handle, err := load(path, FormatAuto, options)
+		if err != nil {
+			return nil, karma.
+				Describe("path", path).
+				Format(
+					err,
+					"unable to load fallback font file",
+				)
+		}
+
+		font.fallbacks = append(font.fallbacks, &face{
+			handle: handle,
+			glyphs: make(map[string]*Glyph),
+		})
+	}
+
+	font.faces[StyleRegular].glyphs = font.Glyphs
+
+	font.size = size
+	font.dpi = dpi
+
+	if cache != "" {
+		key, err := getCacheKey(
+			paths,
+			fallbacks,
+			size,
+			dpi,
+			hinting,
+			format,
+			font.builtin.enabled,
+			font.spacing.line,
+			font.spacing.letter,
 		)
+		if err == nil {
+			font.cache.path = filepath.Join(string(cache), key)
+		}
+	}
+
+	// Missing or outdated cache is not an error, atlas is just prepared
+	// from scratch.
+	if font.cache.path == "" || font.restore() != nil {
+		font.prepare()
+
+		font.cache.dirty = true
 	}
 
 	return font, nil
 }
 
+// Derive loads same font with different path, size or DPI, keeping all
+// other options font was loaded with. Zero values keep current path, size
+// or DPI. Additional options override options font was loaded with.
+func (font *Font) Derive(
+	path string,
+	size float64,
+	dpi float64,
+	extra ...interface{},
+) (*Font, error) {
+	if path == "" {
+		path = font.path
+	}
+
+	if size == 0 {
+		size = font.size
+	}
+
+	if dpi == 0 {
+		dpi = font.dpi
+	}
+
+	opts := append([]interface{}{}, font.options...)
+
+	// Options are applied in order, so these will override previous ones.
+	opts = append(opts, FontSize(size), FontDPI(dpi))
+
+	// Format is specified for main font file, so it doesn't apply to
+	// another file.
+	if path != font.path {
+		opts = append(opts, FontFormat(FormatAuto))
+	}
+	opts = append(opts, extra...)
+
+	return Load(path, opts...)
+}
+
+func (font *Font) GetPath() string {
+	return font.path
+}
+
+func (font *Font) GetSize() float64 {
+	return font.size
+}
+
+func (font *Font) GetDPI() float64 {
+	return font.dpi
+}
+
+// GetLineHeight returns height of the cell in percents of line height of
+// the font.
+func (font *Font) GetLineHeight() int {
+	return font.spacing.line
+}
+
+// GetLetterSpacing returns amount of pixels added to width of the cell.
+func (font *Font) GetLetterSpacing() int {
+	return font.spacing.letter
+}
+
 func (font *Font) GetWidth() int {
 	return font.metrics.width
 }
@@ -90,125 +327,276 @@ func (font *Font) GetHeight() int {
 	return font.metrics.height
 }
 
-func (font *Font) load(path string) error {
-	body, err := ioutil.ReadFile(path)
-	if err != nil {
-		return karma.Format(
-			err,
-			"unable to read font file",
-		)
+// GetBaseline returns distance in pixels from top of the cell to the
+// baseline of glyphs.
+func (font *Font) GetBaseline() int {
+	return font.metrics.height + font.metrics.descender
+}
+
+// GetGlyph returns glyph for given char in specified style. If font has no
+// face for given style, then glyph of closest face is returned.
+//
+// Char can be grapheme cluster of several runes, in which case glyph is
+// composed from glyphs of every rune and added to the atlas.
+//
+// Glyph is rasterized into atlas on first use.
+func (font *Font) GetGlyph(char string, style Style) *Glyph {
+	font.Lock()
+	defer font.Unlock()
+
+	glyph := font.getGlyph(char, style)
+	if glyph == nil && utf8.RuneCountInString(char) > 1 {
+		return font.getCluster(char, style)
 	}
 
-	font.handle, err = truetype.Parse(body)
-	if err != nil {
-		return karma.Format(
-			err,
-			"unable to parse font",
-		)
+	return glyph
+}
+
+func (font *Font) getGlyph(char string, style Style) *Glyph {
+	if glyph := font.getBuiltinGlyph(char); glyph != nil {
+		return glyph
+	}
+
+	for _, style := range style.fallback() {
+		face, ok := font.faces[style]
+		if !ok {
+			continue
+		}
+
+		if glyph := font.getFaceGlyph(face, style, char); glyph != nil {
+			return glyph
+		}
+	}
+
+	// Fallback fonts are used only for regular style, which is always
+	// last one in list of styles to try.
+	for _, fallback := range font.fallbacks {
+		glyph := font.getFaceGlyph(fallback, StyleRegular, char)
+		if glyph != nil {
+			return glyph
+		}
 	}
 
 	return nil
 }
 
-// raster converts given vector font into rasterized image that contains all
-// characters defined in font.
+// getFaceGlyph returns glyph for given char from specified face,
+// rasterizing it if it's not rasterized yet. Chars which are missing in
+// face are remembered as well.
+func (font *Font) getFaceGlyph(face *face, style Style, char string) *Glyph {
+	if glyph, ok := face.glyphs[char]; ok {
+		return glyph
+	}
+
+	glyph, err := font.rasterGlyph(face.handle, style, char)
+	if err != nil {
+		// Glyph which can't be rasterized is drawn as missing char.
+		glyph = nil
+	}
+
+	face.glyphs[char] = glyph
+
+	font.cache.dirty = true
+
+	return glyph
+}
+
+// prepare calculates font metrics and creates atlas image, which contains
+// only blank cell and replacement glyph at first. Other glyphs are
+// rasterized into atlas on first use.
 //
 // Rasterizer image defines several constraints:
 // - all glyphs must fit into fixed-size grid;
 // - cell size of the grid is estimated by advance width and height of most
 //   glyphs in the font;
-// - double-width glyphs are skipped;
+// - double-width glyphs occupy two adjacent cells in the same row;
+// - glyphs wider than two cells are skipped;
 // - rasterized glyphs that doesn't fit into cell size will be clipped;
-func (font *Font) raster(
-	dpi float64,
-	size float64,
-	hinting xfont.Hinting,
-) error {
-	context := freetype.NewContext()
+// - additional faces and fallback fonts are rasterized using metrics of
+//   regular face;
+// - cell is enlarged by line height and letter spacing, glyphs are centered
+//   in enlarged cell;
+// - first cell is left blank for chars without outlines, like space;
+// - second cell holds replacement glyph for chars missing in all fonts;
+func (font *Font) prepare() {
+	font.metrics = font.faces[StyleRegular].handle.getMetrics()
+
+	font.space()
+
+	font.Image = image.NewRGBA(
+		image.Rect(
+			0,
+			0,
+			AtlasColumns*font.metrics.width,
+			AtlasRows*font.metrics.height,
+		),
+	)
 
-	context.SetDPI(dpi)
-	context.SetFontSize(size)
-	context.SetHinting(hinting)
-	context.SetFont(font.handle)
+	font.atlas.columns = AtlasColumns
 
-	font.estimateMetrics(context.GetScale())
+	font.blank.row, font.blank.column = font.allocate(1)
 
-	var (
-		cells = int(math.Ceil(math.Sqrt(float64(font.metrics.length))))
+	font.Replacement = font.rasterReplacement()
+}
 
-		width  = cells * font.metrics.width
-		height = cells * font.metrics.height
+// space enlarges cell by line height and letter spacing. Extra space is
+// split evenly between both sides of the cell, so glyphs stay centered.
+// Negative spacing shrinks the cell, but cell is never smaller than one
+// pixel.
+func (font *Font) space() {
+	var (
+		width  = font.metrics.width + font.spacing.letter
+		height = (font.metrics.height*font.spacing.line + 50) / 100
 	)
 
-	font.Image = image.NewRGBA(image.Rect(0, 0, width, height))
+	if width < 1 {
+		width = 1
+	}
+
+	if height < 1 {
+		height = 1
+	}
 
 	var (
-		column = 0
-		row    = 0
+		horizontal = width - font.metrics.width
+		vertical   = height - font.metrics.height
 	)
 
-	for _, segment := range font.handle.Chars() {
-		for char := segment.Start; char < segment.End; char++ {
-			drawn, err := font.rasterChar(context, row, column, char)
-			if err != nil {
-				return err
-			}
+	font.metrics.advance = font.metrics.width
+	font.metrics.left = horizontal / 2
 
-			if drawn {
-				font.Glyphs[string(char)] = &Glyph{
-					Row:    row,
-					Column: column,
-					Char:   string(char),
-				}
+	font.metrics.width = width
+	font.metrics.height = height
 
-				column++
+	// Descender is distance from bottom of the cell to the baseline, so it
+	// grows by extra space below glyphs.
+	font.metrics.descender -= vertical - vertical/2
+}
 
-				if column >= cells {
-					row++
-					column = 0
-				}
-			}
-		}
+// rasterGlyph rasterizes given char into next free atlas cells. Nil glyph
+// is returned if char is missing in font.
+func (font *Font) rasterGlyph(
+	handle typeface,
+	style Style,
+	char string,
+) (*Glyph, error) {
+	chars := []rune(char)
+	if len(chars) != 1 || !handle.has(chars[0]) {
+		return nil, nil
 	}
 
-	return nil
+	span := font.getSpan(handle, chars[0])
+	if span == 0 {
+		return nil, nil
+	}
+
+	row, column := font.peek(span)
+
+	drawn, err := font.rasterChar(
+		handle,
+		row,
+		column,
+		span,
+		chars[0],
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	glyph := &Glyph{
+		Row:    font.blank.row,
+		Column: font.blank.column,
+		Char:   char,
+		Style:  style,
+	}
+
+	if drawn {
+		font.allocate(span)
+
+		glyph.Row = row
+		glyph.Column = column
+		glyph.Wide = span == 2
+
+		font.atlas.add(glyph)
+	}
+
+	return glyph, nil
 }
 
-func (font *Font) rasterChar(
-	context *freetype.Context,
-	row int,
-	column int,
-	char rune,
-) (bool, error) {
-	index := font.handle.Index(char)
-	width := font.handle.HMetric(context.GetScale(), index).AdvanceWidth.Ceil()
+// rasterReplacement draws box in next free atlas cell, which is used as
+// glyph for chars which are missing in all fonts.
+func (font *Font) rasterReplacement() *Glyph {
+	row, column := font.allocate(1)
 
-	point := fixed.Point26_6{
-		X: fixed.I(font.metrics.width * column),
-		Y: fixed.I(font.metrics.height*(row+1) + font.metrics.descender),
+	var (
+		width    = font.metrics.width
+		baseline = font.GetBaseline()
+
+		left = column*width + 1
+		top  = row*font.metrics.height + 1
+
+		right  = (column+1)*width - 2
+		bottom = row*font.metrics.height + baseline - 1
+	)
+
+	for x := left; x <= right; x++ {
+		font.Image.Set(x, top, color.Black)
+		font.Image.Set(x, bottom, color.Black)
 	}
 
-	// TODO: Support double width characters, which we skip now.
-	if width > font.metrics.width {
-		return false, nil
+	for y := top; y <= bottom; y++ {
+		font.Image.Set(left, y, color.Black)
+		font.Image.Set(right, y, color.Black)
 	}
 
-	_, mask, offset, err := context.Glyph(index, point)
+	glyph := &Glyph{
+		Row:    row,
+		Column: column,
+		Char:   string(utf8.RuneError),
+	}
+
+	font.atlas.add(glyph)
+
+	return glyph
+}
+
+func (font *Font) rasterChar(
+	handle typeface,
+	row int,
+	column int,
+	span int,
+	char rune,
+) (bool, error) {
+	mask, offset, err := handle.getMask(char)
 	if err != nil {
 		return false, karma.Format(
 			err,
-			")unable to raster glyph %c",
+			"unable to raster glyph %c",
 			char,
 		)
 	}
 
+	if mask == nil {
+		return false, nil
+	}
+
+	// Mask offset is relative to glyph origin on baseline. Letter spacing
+	// is added on both sides of every cell glyph spans, so wide glyphs are
+	// centered too.
+	offset = offset.Add(
+		image.Pt(
+			font.metrics.width*column+font.metrics.left*span,
+			font.metrics.height*(row+1)+font.metrics.descender,
+		),
+	)
+
 	// Some glyphs be bigger, than cell in our rendering grid, so we need to
 	// clip them into cell size.
 	var (
 		cell = image.Rect(
 			font.metrics.width*column,
 			font.metrics.height*row,
-			font.metrics.width*(column+1),
+			font.metrics.width*(column+span),
 			font.metrics.height*(row+1),
 		)
 
@@ -235,6 +623,8 @@ func (font *Font) rasterChar(
 		pivot.Y = 0
 	}
 
+	font.atlas.changed(cell)
+
 	draw.DrawMask(
 		font.Image, box,
 		image.Black, image.ZP,
@@ -245,54 +635,19 @@ func (font *Font) rasterChar(
 	return true, nil
 }
 
-// estimateMetrics loops over every glyph in font to calculate following
-// metrics:
-// - total amount of characters defined in font;
-// - advance width of most characters in font;
-// - advance height of most characters in font;
-// - font descender if any.
-func (font *Font) estimateMetrics(scale fixed.Int26_6) {
-	length := 0
-
-	var (
-		widths  = map[int]int{}
-		heights = map[int]int{}
-	)
-
-	for _, segment := range font.handle.Chars() {
-		length += int(segment.End - segment.Start)
-
-		for char := segment.Start; char < segment.End; char++ {
-			var (
-				index  = font.handle.Index(char)
-				width  = font.handle.HMetric(scale, index).AdvanceWidth.Ceil()
-				height = font.handle.VMetric(scale, index).AdvanceHeight.Ceil()
-			)
-
-			widths[width]++
-			heights[height]++
-		}
-	}
-
-	most := func(measures map[int]int) int {
-		var (
-			max    = 0
-			result = 0
-		)
-
-		for measure, count := range measures {
-			if count > max {
-				max = count
-				result = measure
-			}
-		}
-
-		return result
+// getSpan returns amount of cells which are required to raster given char:
+// 1 for regular glyphs, 2 for double-width glyphs and 0 for glyphs which
+// are too wide to be rendered. Advance is compared with cell width without
+// letter spacing.
+func (font *Font) getSpan(handle typeface, char rune) int {
+	width := handle.getAdvance(char)
+
+	switch {
+	case width <= font.metrics.advance:
+		return 1
+	case width <= font.metrics.advance*2:
+		return 2
+	default:
+		return 0
 	}
-
-	font.metrics.length = length
-	font.metrics.width = most(widths)
-	font.metrics.height = most(heights)
-
-	font.metrics.descender = font.handle.GetDescender().Ceil()
 }
@@ -0,0 +1,96 @@
+package fonts
+
+import (
+	"image"
+	"image/draw"
+
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+
+	xfont "golang.org/x/image/font"
+)
+
+// openType is typeface of OpenType font, which can have either TrueType
+// or CFF outlines. It is used for fonts which can't be parsed by TrueType
+// parser, like CFF-flavored `.otf` files.
+type openType struct {
+	handle *sfnt.Font
+	face   xfont.Face
+	buffer sfnt.Buffer
+}
+
+func parseOpenType(body []byte, options rasterOptions) (*openType, error) {
+	handle, err := opentype.Parse(body)
+	if err != nil {
+		return nil, err
+	}
+
+	face, err := opentype.NewFace(handle, &opentype.FaceOptions{
+		Size:    options.size,
+		DPI:     options.dpi,
+		Hinting: options.hinting,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &openType{
+		handle: handle,
+		face:   face,
+	}, nil
+}
+
+func (face *openType) has(char rune) bool {
+	index, err := face.handle.GlyphIndex(&face.buffer, char)
+
+	return err == nil && index != 0
+}
+
+func (face *openType) getAdvance(char rune) int {
+	advance, ok := face.face.GlyphAdvance(char)
+	if !ok {
+		return 0
+	}
+
+	return advance.Ceil()
+}
+
+func (face *openType) getMask(char rune) (*image.Alpha, image.Point, error) {
+	bounds, mask, point, _, ok := face.face.Glyph(fixed.Point26_6{}, char)
+	if !ok {
+		return nil, image.ZP, nil
+	}
+
+	// Face reuses mask buffer for every glyph, so mask should be copied.
+	alpha := image.NewAlpha(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+
+	draw.Draw(alpha, alpha.Rect, mask, point, draw.Src)
+
+	return alpha, bounds.Min, nil
+}
+
+// getMetrics calculates cell metrics of font. OpenType fonts don't provide
+// list of defined chars, so cell width is estimated by advance width of
+// most printable ASCII chars.
+func (face *openType) getMetrics() metrics {
+	widths := map[int]int{}
+
+	for char := rune(' '); char <= '~'; char++ {
+		if !face.has(char) {
+			continue
+		}
+
+		widths[face.getAdvance(char)]++
+	}
+
+	extents := face.face.Metrics()
+
+	return metrics{
+		length: face.handle.NumGlyphs(),
+		width:  getMostCommon(widths),
+		height: (extents.Ascent + extents.Descent).Ceil(),
+
+		descender: -extents.Descent.Ceil(),
+	}
+}
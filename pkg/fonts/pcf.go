@@ -0,0 +1,536 @@
+package fonts
+
+import (
+	"encoding/binary"
+	"image"
+
+	"github.com/reconquest/karma-go"
+)
+
+// PCF table types, see X11 `pcf.h`.
+const (
+	pcfProperties      = 1 << 0
+	pcfAccelerators    = 1 << 1
+	pcfMetrics         = 1 << 2
+	pcfBitmaps         = 1 << 3
+	pcfBDFEncodings    = 1 << 5
+	pcfBDFAccelerators = 1 << 8
+)
+
+// PCF table format flags.
+const (
+	pcfCompressedMetrics = 0x100
+
+	pcfGlyphPadMask = 3 << 0
+	pcfByteMask     = 1 << 2
+	pcfBitMask      = 1 << 3
+	pcfScanUnitMask = 3 << 4
+)
+
+const pcfMissingGlyph = 0xffff
+
+type pcfTable struct {
+	format uint32
+	offset uint32
+	size   uint32
+}
+
+type pcfMetric struct {
+	left    int
+	right   int
+	advance int
+	ascent  int
+	descent int
+}
+
+// pcfReader reads values from PCF table in byte order specified by table
+// format.
+type pcfReader struct {
+	data   []byte
+	format uint32
+	order  binary.ByteOrder
+	offset int
+}
+
+// parsePCF parses font in X11 Portable Compiled Format. Glyph encodings are
+// treated as Unicode code points, so only fonts with ISO10646 or ISO8859-1
+// charset are displayed correctly.
+func parsePCF(body []byte, options rasterOptions) (*bitmap, error) {
+	if len(body) < 8 || string(body[:4]) != "\x01fcp" {
+		return nil, karma.Format(
+			ErrMalformedFont,
+			"PCF header is missing",
+		)
+	}
+
+	var (
+		count  = int(binary.LittleEndian.Uint32(body[4:]))
+		tables = map[uint32]pcfTable{}
+	)
+
+	if len(body) < 8+count*16 {
+		return nil, karma.Format(
+			ErrMalformedFont,
+			"PCF table of contents is truncated",
+		)
+	}
+
+	for i := 0; i < count; i++ {
+		entry := body[8+i*16:]
+
+		tables[binary.LittleEndian.Uint32(entry)] = pcfTable{
+			format: binary.LittleEndian.Uint32(entry[4:]),
+			size:   binary.LittleEndian.Uint32(entry[8:]),
+			offset: binary.LittleEndian.Uint32(entry[12:]),
+		}
+	}
+
+	table := func(kind uint32) (*pcfReader, error) {
+		entry, ok := tables[kind]
+		if !ok {
+			return nil, nil
+		}
+
+		if uint64(entry.offset)+uint64(entry.size) > uint64(len(body)) ||
+			entry.size < 4 {
+			return nil, karma.
+				Describe("table", kind).
+				Format(
+					ErrMalformedFont,
+					"PCF table is out of file bounds",
+				)
+		}
+
+		return newPCFReader(body[entry.offset : entry.offset+entry.size]), nil
+	}
+
+	face := newBitmap()
+
+	reader, err := table(pcfProperties)
+	if err != nil {
+		return nil, err
+	}
+
+	if reader != nil {
+		face.resolution = reader.readResolution()
+	}
+
+	// BDF accelerators are more accurate, if font has them.
+	reader, err = table(pcfBDFAccelerators)
+	if err == nil && reader == nil {
+		reader, err = table(pcfAccelerators)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if reader == nil {
+		return nil, karma.Format(
+			ErrMalformedFont,
+			"PCF font has no accelerators table",
+		)
+	}
+
+	face.ascent, face.descent = reader.readAccelerators()
+
+	reader, err = table(pcfMetrics)
+	if err != nil {
+		return nil, err
+	}
+
+	if reader == nil {
+		return nil, karma.Format(
+			ErrMalformedFont,
+			"PCF font has no metrics table",
+		)
+	}
+
+	glyphs := reader.readMetrics()
+
+	reader, err = table(pcfBitmaps)
+	if err != nil {
+		return nil, err
+	}
+
+	if reader == nil {
+		return nil, karma.Format(
+			ErrMalformedFont,
+			"PCF font has no bitmaps table",
+		)
+	}
+
+	bitmaps, stride := reader.readBitmaps(glyphs)
+
+	reader, err = table(pcfBDFEncodings)
+	if err != nil {
+		return nil, err
+	}
+
+	if reader == nil {
+		return nil, karma.Format(
+			ErrMalformedFont,
+			"PCF font has no encodings table",
+		)
+	}
+
+	encodings, err := reader.readEncodings()
+	if err != nil {
+		return nil, err
+	}
+
+	for char, index := range encodings {
+		if index >= len(glyphs) || index >= len(bitmaps) {
+			continue
+		}
+
+		metric := glyphs[index]
+
+		face.add(
+			char,
+			metric.advance,
+			image.Rect(
+				metric.left,
+				-metric.ascent,
+				metric.right,
+				metric.descent,
+			),
+			bitmaps[index],
+			stride(metric),
+		)
+	}
+
+	if len(face.glyphs) == 0 {
+		return nil, karma.Format(
+			ErrMalformedFont,
+			"PCF font has no glyphs",
+		)
+	}
+
+	face.setScale(options)
+
+	return face, nil
+}
+
+// newPCFReader returns reader for table data, which starts with table
+// format in little endian byte order.
+func newPCFReader(data []byte) *pcfReader {
+	reader := &pcfReader{
+		data:   data,
+		format: binary.LittleEndian.Uint32(data),
+		offset: 4,
+		order:  binary.LittleEndian,
+	}
+
+	if reader.format&pcfByteMask != 0 {
+		reader.order = binary.BigEndian
+	}
+
+	return reader
+}
+
+// Reads past the end of table return zeroes, so truncated tables produce
+// empty glyphs instead of panics.
+func (reader *pcfReader) read(size int) []byte {
+	if size < 0 {
+		size = 0
+	}
+
+	if reader.offset+size > len(reader.data) {
+		reader.offset = len(reader.data)
+
+		return make([]byte, size)
+	}
+
+	data := reader.data[reader.offset : reader.offset+size]
+
+	reader.offset += size
+
+	return data
+}
+
+func (reader *pcfReader) uint8() int {
+	return int(reader.read(1)[0])
+}
+
+func (reader *pcfReader) int16() int {
+	return int(int16(reader.order.Uint16(reader.read(2))))
+}
+
+func (reader *pcfReader) uint16() int {
+	return int(reader.order.Uint16(reader.read(2)))
+}
+
+func (reader *pcfReader) int32() int {
+	return int(int32(reader.order.Uint32(reader.read(4))))
+}
+
+func (reader *pcfReader) skip(size int) {
+	reader.read(size)
+}
+
+// limit returns amount of items of given size which can be read from the
+// rest of table, but not more than specified count.
+func (reader *pcfReader) limit(count int, size int) int {
+	left := (len(reader.data) - reader.offset) / size
+
+	switch {
+	case count < 0:
+		return 0
+	case count > left:
+		return left
+	default:
+		return count
+	}
+}
+
+// readResolution returns RESOLUTION_X property of font or zero if font
+// doesn't declare it.
+func (reader *pcfReader) readResolution() int {
+	count := reader.limit(reader.int32(), 9)
+
+	type property struct {
+		name     int
+		isString bool
+		value    int
+	}
+
+	properties := make([]property, 0, count)
+
+	for i := 0; i < count; i++ {
+		properties = append(properties, property{
+			name:     reader.int32(),
+			isString: reader.uint8() != 0,
+			value:    reader.int32(),
+		})
+	}
+
+	// Properties are padded to 4 bytes.
+	if count%4 != 0 {
+		reader.skip(4 - count%4)
+	}
+
+	var (
+		size    = reader.int32()
+		strings = reader.read(size)
+	)
+
+	for _, property := range properties {
+		if property.isString || property.name < 0 ||
+			property.name >= len(strings) {
+			continue
+		}
+
+		if readString(strings[property.name:]) == "RESOLUTION_X" {
+			return property.value
+		}
+	}
+
+	return 0
+}
+
+// readAccelerators returns ascent and descent of font.
+func (reader *pcfReader) readAccelerators() (int, int) {
+	// Skip flags: noOverlap, constantMetrics, terminalFont, constantWidth,
+	// inkInside, inkMetrics, drawDirection and padding.
+	reader.skip(8)
+
+	var (
+		ascent  = reader.int32()
+		descent = reader.int32()
+	)
+
+	return ascent, descent
+}
+
+func (reader *pcfReader) readMetrics() []pcfMetric {
+	var (
+		compressed = reader.format&pcfCompressedMetrics != 0
+		count      int
+	)
+
+	if compressed {
+		count = reader.limit(reader.int16(), 5)
+	} else {
+		count = reader.limit(reader.int32(), 12)
+	}
+
+	glyphs := make([]pcfMetric, 0, count)
+
+	for i := 0; i < count; i++ {
+		var metric pcfMetric
+
+		if compressed {
+			metric = pcfMetric{
+				left:    reader.uint8() - 0x80,
+				right:   reader.uint8() - 0x80,
+				advance: reader.uint8() - 0x80,
+				ascent:  reader.uint8() - 0x80,
+				descent: reader.uint8() - 0x80,
+			}
+		} else {
+			metric = pcfMetric{
+				left:    reader.int16(),
+				right:   reader.int16(),
+				advance: reader.int16(),
+				ascent:  reader.int16(),
+				descent: reader.int16(),
+			}
+
+			// Skip attributes.
+			reader.skip(2)
+		}
+
+		glyphs = append(glyphs, metric)
+	}
+
+	return glyphs
+}
+
+// readBitmaps returns bitmaps of glyphs, converted to most significant bit
+// first order, and function which returns row stride of glyph bitmap.
+func (reader *pcfReader) readBitmaps(
+	glyphs []pcfMetric,
+) ([][]byte, func(pcfMetric) int) {
+	count := reader.limit(reader.int32(), 4)
+
+	offsets := make([]int, 0, count)
+	for i := 0; i < count; i++ {
+		offsets = append(offsets, reader.int32())
+	}
+
+	// Skip sizes of bitmap data for every possible glyph padding.
+	reader.skip(16)
+
+	var (
+		data = reader.data[reader.offset:]
+
+		pad  = 1 << (reader.format & pcfGlyphPadMask)
+		unit = 1 << ((reader.format & pcfScanUnitMask) >> 4)
+
+		bigEndian = reader.format&pcfByteMask != 0
+		msbFirst  = reader.format&pcfBitMask != 0
+	)
+
+	stride := func(metric pcfMetric) int {
+		bytes := (metric.right - metric.left + 7) / 8
+
+		return (bytes + pad - 1) / pad * pad
+	}
+
+	bitmaps := make([][]byte, 0, count)
+
+	for i, offset := range offsets {
+		if i >= len(glyphs) {
+			break
+		}
+
+		var (
+			metric = glyphs[i]
+			size   = stride(metric) * (metric.ascent + metric.descent)
+		)
+
+		if offset < 0 || size < 0 || offset+size > len(data) {
+			bitmaps = append(bitmaps, nil)
+
+			continue
+		}
+
+		bits := append([]byte{}, data[offset:offset+size]...)
+
+		if !msbFirst {
+			for j := range bits {
+				bits[j] = reverseBits(bits[j])
+			}
+		}
+
+		// Bytes are swapped within scan units if byte order differs from
+		// bit order.
+		if bigEndian != msbFirst && unit > 1 {
+			for j := 0; j+unit <= len(bits); j += unit {
+				for k := 0; k < unit/2; k++ {
+					bits[j+k], bits[j+unit-1-k] = bits[j+unit-1-k], bits[j+k]
+				}
+			}
+		}
+
+		bitmaps = append(bitmaps, bits)
+	}
+
+	return bitmaps, stride
+}
+
+// readEncodings returns map of chars to glyph indices. Chars which are
+// past the end of truncated table are missing.
+func (reader *pcfReader) readEncodings() (map[rune]int, error) {
+	var (
+		minByte2 = reader.int16()
+		maxByte2 = reader.int16()
+		minByte1 = reader.int16()
+		maxByte1 = reader.int16()
+	)
+
+	for _, bound := range []int{minByte1, maxByte1, minByte2, maxByte2} {
+		if bound < 0 || bound > 0xff {
+			return nil, karma.Format(
+				ErrMalformedFont,
+				"PCF encodings table has invalid byte bounds",
+			)
+		}
+	}
+
+	if minByte1 > maxByte1 || minByte2 > maxByte2 {
+		return nil, karma.Format(
+			ErrMalformedFont,
+			"PCF encodings table has invalid byte bounds",
+		)
+	}
+
+	// Skip default char.
+	reader.skip(2)
+
+	var (
+		columns = maxByte2 - minByte2 + 1
+		rows    = maxByte1 - minByte1 + 1
+		count   = reader.limit(columns*rows, 2)
+
+		encodings = map[rune]int{}
+	)
+
+	for i := 0; i < count; i++ {
+		var (
+			byte1 = minByte1 + i/columns
+			byte2 = minByte2 + i%columns
+			index = reader.uint16()
+		)
+
+		if index == pcfMissingGlyph {
+			continue
+		}
+
+		encodings[rune(byte1<<8|byte2)] = index
+	}
+
+	return encodings, nil
+}
+
+func readString(data []byte) string {
+	for i, char := range data {
+		if char == 0 {
+			return string(data[:i])
+		}
+	}
+
+	return string(data)
+}
+
+func reverseBits(value byte) byte {
+	var result byte
+
+	for i := 0; i < 8; i++ {
+		result = result<<1 | value&1
+		value >>= 1
+	}
+
+	return result
+}
@@ -0,0 +1,275 @@
+package fonts
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sort"
+	"testing"
+)
+
+// testPCF is builder of PCF fonts with glyphs A and B of test BDF font.
+// Tables are stored in little endian order with bitmaps padded to bytes.
+type testPCF struct {
+	tables map[uint32][]byte
+}
+
+func newTestPCF() *testPCF {
+	var (
+		glyphA = []byte{0x60, 0x90, 0x90, 0xf0, 0x90, 0x90, 0x00, 0x00}
+		glyphB = []byte{0xe0, 0x90, 0xe0, 0x90, 0x90, 0xe0, 0x00, 0x00}
+	)
+
+	return &testPCF{
+		tables: map[uint32][]byte{
+			pcfAccelerators: pack(
+				uint32(0),
+				[8]byte{},
+				int32(6),
+				int32(2),
+			),
+
+			pcfMetrics: pack(
+				uint32(0),
+				int32(2),
+				[]int16{0, 4, 4, 6, 2, 0},
+				[]int16{0, 4, 4, 6, 2, 0},
+			),
+
+			pcfBitmaps: pack(
+				uint32(pcfBitMask),
+				int32(2),
+				[]int32{0, int32(len(glyphA))},
+				[4]int32{},
+				glyphA,
+				glyphB,
+			),
+
+			pcfBDFEncodings: pack(
+				uint32(0),
+				[]int16{'A', 'B', 0, 0, 0},
+				[]uint16{0, 1},
+			),
+		},
+	}
+}
+
+// bytes returns font file with table of contents followed by tables.
+func (font *testPCF) bytes() []byte {
+	kinds := []uint32{}
+	for kind := range font.tables {
+		kinds = append(kinds, kind)
+	}
+
+	sort.Slice(kinds, func(i, j int) bool { return kinds[i] < kinds[j] })
+
+	var (
+		header = pack([4]byte{1, 'f', 'c', 'p'}, uint32(len(kinds)))
+		offset = len(header) + len(kinds)*16
+		body   []byte
+	)
+
+	for _, kind := range kinds {
+		table := font.tables[kind]
+
+		header = append(header, pack(
+			kind,
+			binary.LittleEndian.Uint32(table),
+			uint32(len(table)),
+			uint32(offset+len(body)),
+		)...)
+
+		body = append(body, table...)
+	}
+
+	return append(header, body...)
+}
+
+func pack(values ...interface{}) []byte {
+	buffer := &bytes.Buffer{}
+
+	for _, value := range values {
+		err := binary.Write(buffer, binary.LittleEndian, value)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	return buffer.Bytes()
+}
+
+func TestParsePCF(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(*testPCF)
+		present string
+		missing string
+	}{
+		{
+			name:    "complete",
+			present: "AB",
+			missing: "C@",
+		},
+		{
+			name: "big endian bitmaps",
+			modify: func(font *testPCF) {
+				bitmaps := font.tables[pcfBitmaps]
+
+				binary.LittleEndian.PutUint32(bitmaps, pcfBitMask|pcfByteMask)
+				binary.BigEndian.PutUint32(bitmaps[4:], 2)
+				binary.BigEndian.PutUint32(bitmaps[8:], 0)
+				binary.BigEndian.PutUint32(bitmaps[12:], 8)
+			},
+			present: "AB",
+		},
+		{
+			name: "missing glyph",
+			modify: func(font *testPCF) {
+				encodings := font.tables[pcfBDFEncodings]
+
+				binary.LittleEndian.PutUint16(
+					encodings[len(encodings)-2:],
+					pcfMissingGlyph,
+				)
+			},
+			present: "A",
+			missing: "B",
+		},
+		{
+			name: "truncated encodings",
+			modify: func(font *testPCF) {
+				encodings := font.tables[pcfBDFEncodings]
+
+				font.tables[pcfBDFEncodings] = encodings[:len(encodings)-2]
+			},
+			present: "A",
+			missing: "B",
+		},
+		{
+			name: "truncated bitmaps",
+			modify: func(font *testPCF) {
+				bitmaps := font.tables[pcfBitmaps]
+
+				font.tables[pcfBitmaps] = bitmaps[:len(bitmaps)-1]
+			},
+			present: "AB",
+		},
+		{
+			name: "truncated metrics",
+			modify: func(font *testPCF) {
+				metrics := font.tables[pcfMetrics]
+
+				font.tables[pcfMetrics] = metrics[:len(metrics)-12]
+			},
+			present: "A",
+			missing: "B",
+		},
+	}
+
+	for _, test := range tests {
+		font := newTestPCF()
+		if test.modify != nil {
+			test.modify(font)
+		}
+
+		face, err := parsePCF(font.bytes(), rasterOptions{dpi: 72, size: 8})
+		if err != nil {
+			t.Errorf("%s: unable to parse font: %s", test.name, err)
+
+			continue
+		}
+
+		t.Run(test.name, func(t *testing.T) {
+			assertBitmap(t, face, test.present, test.missing)
+		})
+	}
+}
+
+func TestParsePCF_Malformed(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func([]byte) []byte
+	}{
+		{
+			name: "empty",
+			modify: func([]byte) []byte {
+				return nil
+			},
+		},
+		{
+			name: "invalid header",
+			modify: func(body []byte) []byte {
+				body[1] = 'x'
+
+				return body
+			},
+		},
+		{
+			name: "truncated table of contents",
+			modify: func(body []byte) []byte {
+				return body[:8+16]
+			},
+		},
+		{
+			name: "truncated table",
+			modify: func(body []byte) []byte {
+				return body[:len(body)-1]
+			},
+		},
+	}
+
+	for _, test := range tests {
+		body := test.modify(newTestPCF().bytes())
+
+		_, err := parsePCF(body, rasterOptions{dpi: 72, size: 8})
+		if err == nil {
+			t.Errorf("%s: expected error", test.name)
+		}
+	}
+}
+
+func TestParsePCF_MissingTable(t *testing.T) {
+	for _, kind := range []uint32{
+		pcfAccelerators,
+		pcfMetrics,
+		pcfBitmaps,
+		pcfBDFEncodings,
+	} {
+		font := newTestPCF()
+
+		delete(font.tables, kind)
+
+		_, err := parsePCF(font.bytes(), rasterOptions{dpi: 72, size: 8})
+		if err == nil {
+			t.Errorf("table %d: expected error", kind)
+		}
+	}
+}
+
+func TestParsePCF_InvalidEncodingBounds(t *testing.T) {
+	tests := []struct {
+		name   string
+		bounds []int16
+	}{
+		{"min byte2 above max", []int16{'B', 'A', 0, 0}},
+		{"min byte1 above max", []int16{'A', 'B', 1, 0}},
+		{"negative byte2", []int16{-1, 'B', 0, 0}},
+		{"byte2 above 0xff", []int16{'A', 0x100, 0, 0}},
+		{"byte1 above 0xff", []int16{'A', 'B', 0, 0x100}},
+	}
+
+	for _, test := range tests {
+		font := newTestPCF()
+
+		font.tables[pcfBDFEncodings] = pack(
+			uint32(0),
+			test.bounds,
+			int16(0),
+			[]uint16{0, 1},
+		)
+
+		_, err := parsePCF(font.bytes(), rasterOptions{dpi: 72, size: 8})
+		if err == nil {
+			t.Errorf("%s: expected error", test.name)
+		}
+	}
+}
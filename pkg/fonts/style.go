@@ -0,0 +1,45 @@
+package fonts
+
+// Style specifies font face which is used to raster glyph.
+type Style int
+
+const (
+	StyleRegular    Style = 0
+	StyleBold       Style = 1
+	StyleItalic     Style = 2
+	StyleBoldItalic Style = StyleBold | StyleItalic
+)
+
+// styles lists all styles in order they are placed in font atlas.
+var styles = []Style{
+	StyleRegular,
+	StyleBold,
+	StyleItalic,
+	StyleBoldItalic,
+}
+
+func (style Style) String() string {
+	switch style {
+	case StyleBold:
+		return "bold"
+	case StyleItalic:
+		return "italic"
+	case StyleBoldItalic:
+		return "bold italic"
+	default:
+		return "regular"
+	}
+}
+
+// fallback returns list of styles which should be tried in order to find
+// glyph for given style when font lacks corresponding face.
+func (style Style) fallback() []Style {
+	switch style {
+	case StyleBoldItalic:
+		return []Style{StyleBoldItalic, StyleBold, StyleItalic, StyleRegular}
+	case StyleBold, StyleItalic:
+		return []Style{style, StyleRegular}
+	default:
+		return []Style{StyleRegular}
+	}
+}
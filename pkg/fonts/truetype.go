@@ -0,0 +1,118 @@
+package fonts
+
+import (
+	"image"
+
+	"github.com/seletskiy/freetype"
+	"github.com/seletskiy/freetype/truetype"
+	"golang.org/x/image/math/fixed"
+)
+
+// trueType is typeface of TrueType font, which is rasterized at size and
+// DPI font is loaded with.
+type trueType struct {
+	handle  *truetype.Font
+	context *freetype.Context
+}
+
+func parseTrueType(body []byte, options rasterOptions) (*trueType, error) {
+	handle, err := truetype.Parse(body)
+	if err != nil {
+		return nil, err
+	}
+
+	context := freetype.NewContext()
+
+	context.SetDPI(options.dpi)
+	context.SetFontSize(options.size)
+	context.SetHinting(options.hinting)
+	context.SetFont(handle)
+
+	return &trueType{
+		handle:  handle,
+		context: context,
+	}, nil
+}
+
+func (face *trueType) has(char rune) bool {
+	return face.handle.Index(char) != 0
+}
+
+func (face *trueType) getAdvance(char rune) int {
+	index := face.handle.Index(char)
+
+	return face.handle.HMetric(face.context.GetScale(), index).
+		AdvanceWidth.Ceil()
+}
+
+func (face *trueType) getMask(char rune) (*image.Alpha, image.Point, error) {
+	_, mask, offset, err := face.context.Glyph(
+		face.handle.Index(char),
+		fixed.Point26_6{},
+	)
+
+	return mask, offset, err
+}
+
+// getMetrics loops over every glyph in font to calculate following
+// metrics:
+// - total amount of characters defined in font;
+// - advance width of most characters in font;
+// - advance height of most characters in font;
+// - font descender if any.
+func (face *trueType) getMetrics() metrics {
+	var (
+		scale = face.context.GetScale()
+
+		widths  = map[int]int{}
+		heights = map[int]int{}
+	)
+
+	for _, segment := range face.handle.Chars() {
+		for char := segment.Start; char < segment.End; char++ {
+			var (
+				index  = face.handle.Index(char)
+				width  = face.handle.HMetric(scale, index).AdvanceWidth.Ceil()
+				height = face.handle.VMetric(scale, index).AdvanceHeight.Ceil()
+			)
+
+			widths[width]++
+			heights[height]++
+		}
+	}
+
+	return metrics{
+		length: face.countChars(),
+		width:  getMostCommon(widths),
+		height: getMostCommon(heights),
+
+		descender: face.handle.GetDescender().Ceil(),
+	}
+}
+
+func (face *trueType) countChars() int {
+	length := 0
+
+	for _, segment := range face.handle.Chars() {
+		length += int(segment.End - segment.Start)
+	}
+
+	return length
+}
+
+// getMostCommon returns measure which is counted most times.
+func getMostCommon(measures map[int]int) int {
+	var (
+		max    = 0
+		result = 0
+	)
+
+	for measure, count := range measures {
+		if count > max {
+			max = count
+			result = measure
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,155 @@
+package fonts
+
+import (
+	"bytes"
+	"compress/gzip"
+	"image"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/reconquest/karma-go"
+
+	xfont "golang.org/x/image/font"
+)
+
+// FontFormat specifies format of main font file. Format is detected by file
+// extension if not specified, as well as formats of styled and fallback
+// font files.
+type FontFormat string
+
+const (
+	FormatAuto     FontFormat = ""
+	FormatTrueType FontFormat = "truetype"
+	FormatOpenType FontFormat = "opentype"
+	FormatBDF      FontFormat = "bdf"
+	FormatPCF      FontFormat = "pcf"
+)
+
+// typeface is font file parsed in one of supported formats. Every face of
+// font, including fallback fonts, can be of different format, because
+// typeface rasterizes its own glyphs.
+type typeface interface {
+	// getMetrics returns cell metrics of typeface.
+	getMetrics() metrics
+
+	// has reports whether typeface defines given char.
+	has(char rune) bool
+
+	// getAdvance returns advance width of given char in pixels.
+	getAdvance(char rune) int
+
+	// getMask returns alpha mask of given char and offset of mask top left
+	// corner relative to glyph origin on baseline.
+	getMask(char rune) (*image.Alpha, image.Point, error)
+}
+
+type metrics struct {
+	length int
+	width  int
+	height int
+
+	descender int
+
+	// left is offset of glyph origin from the left side of the cell and
+	// advance is width of the cell without letter spacing. Both are set by
+	// Font.space, typefaces leave them zero.
+	left    int
+	advance int
+}
+
+// rasterOptions are parameters typeface is rasterized with. Bitmap fonts
+// have fixed size, so only DPI is used to scale them.
+type rasterOptions struct {
+	dpi     float64
+	size    float64
+	hinting xfont.Hinting
+}
+
+func load(
+	path string,
+	format FontFormat,
+	options rasterOptions,
+) (typeface, error) {
+	body, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, karma.Format(
+			err,
+			"unable to read font file",
+		)
+	}
+
+	// Bitmap fonts are often distributed compressed, like `ter-u14n.pcf.gz`.
+	if strings.HasSuffix(path, ".gz") {
+		reader, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, karma.Format(
+				err,
+				"unable to decompress font file",
+			)
+		}
+
+		body, err = ioutil.ReadAll(reader)
+		if err != nil {
+			return nil, karma.Format(
+				err,
+				"unable to decompress font file",
+			)
+		}
+
+		path = strings.TrimSuffix(path, ".gz")
+	}
+
+	if format == FormatAuto {
+		format = getFormat(path, body)
+	}
+
+	var handle typeface
+
+	switch format {
+	case FormatTrueType:
+		handle, err = parseTrueType(body, options)
+	case FormatOpenType:
+		handle, err = parseOpenType(body, options)
+	case FormatBDF:
+		handle, err = parseBDF(body, options)
+	case FormatPCF:
+		handle, err = parsePCF(body, options)
+	default:
+		return nil, karma.
+			Describe("format", format).
+			Reason(ErrUnknownFormat)
+	}
+
+	if err != nil {
+		return nil, karma.
+			Describe("format", format).
+			Format(
+				err,
+				"unable to parse font",
+			)
+	}
+
+	return handle, nil
+}
+
+// getFormat detects font format by file extension. OpenType fonts with
+// CFF outlines are detected by signature as well, because they are often
+// shipped with `.ttf` extension. Files with unknown extensions are treated
+// as TrueType fonts.
+func getFormat(path string, body []byte) FontFormat {
+	if bytes.HasPrefix(body, []byte("OTTO")) {
+		return FormatOpenType
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".bdf":
+		return FormatBDF
+	case ".pcf":
+		return FormatPCF
+	case ".otf":
+		return FormatOpenType
+	default:
+		return FormatTrueType
+	}
+}
@@ -0,0 +1,153 @@
+package binary
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/seletskiy/mainframe/pkg/protocol/messages"
+	"github.com/seletskiy/mainframe/pkg/protocol/text"
+)
+
+// Messages are constructed via text protocol, so every message type is
+// covered the same way clients send it.
+var roundtripMessages = []string{
+	`ok`,
+	`ok id: 3 offscreen`,
+	`error message: "something went wrong"`,
+	`event tick: 123 kind: "resize" columns: 80 rows: 20 width: 640 height: 480 scale: 200`,
+	`event tick: 123 kind: "keyboard" symbol: "Ctrl+Q" press code: 24 ctrl`,
+	`event tick: 123 kind: "input" char: "ы" shift`,
+	`event kind: "input" char: "q"`,
+	`put x: 1 y: 2 text: "hello, мир"`,
+	`put x: -1 y: -2 columns: 3 rows: 4 fg: #ff0 bg: #12345678 bold italic underline strike reverse dim`,
+	`put x: 0 y: 0 fg: 12 bg: 255 tick: 123 exclusive`,
+	`subscribe resize`,
+	`subscribe keyboard input`,
+	`open`,
+	`open id: 1 width: 640 height: 480 x: -1 y: 2 title: "test" hidden fixed bare raw transparent zoom`,
+	`open columns: 80 rows: 20 font: "/tmp/font.ttf" font_size: 12 font_dpi: 96 line_height: 120 letter_spacing: 1 fg: #fff bg: #000`,
+	`reshape width: 640 height: 480`,
+	`reshape columns: 80 rows: 20 x: 10 y: -10`,
+	`clear`,
+	`clear x: 1 y: 2 columns: 3 rows: 4`,
+	`copy to_x: 0 to_y: 0`,
+	`copy x: 1 y: 2 columns: 3 rows: 4 to_x: -5 to_y: 6 tick: 123`,
+	`move x: 0 y: 1 to_x: 0 to_y: 0`,
+	`capture`,
+	`capture x: 1 y: -1 columns: 3 rows: 2 path: "/tmp/window.png"`,
+	`capture path: "/tmp/window.png" overwrite`,
+	`get font`,
+	`get cells`,
+	`get cells x: 1 y: 2 columns: 3 rows: 4`,
+	`protocol kind: "binary"`,
+	`begin`,
+	`commit`,
+	`abort`,
+	`set font_size: 14 line_height: 110 letter_spacing: 2`,
+	`set fg: #fff bg: #0000`,
+	`set palette: 3 color: #abc`,
+}
+
+func TestRoundtrip(t *testing.T) {
+	for _, line := range roundtripMessages {
+		expected, err := text.Parse(line)
+		if err != nil {
+			t.Fatalf("%s: unable to parse text: %s", line, err)
+		}
+
+		frame := Serialize(expected.(messages.Serializable))
+
+		data, err := Read(bytes.NewReader(frame))
+		if err != nil {
+			t.Fatalf("%s: unable to read frame: %s", line, err)
+		}
+
+		actual, err := Parse(data)
+		if err != nil {
+			t.Fatalf("%s: unable to parse frame: %s", line, err)
+		}
+
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf(
+				"%s: message differs after roundtrip\n"+
+					"expected: %#v\n"+
+					"actual:   %#v",
+				line,
+				expected,
+				actual,
+			)
+		}
+	}
+}
+
+func TestRead_TruncatedFrame(t *testing.T) {
+	for _, line := range roundtripMessages {
+		message, err := text.Parse(line)
+		if err != nil {
+			t.Fatalf("%s: unable to parse text: %s", line, err)
+		}
+
+		frame := Serialize(message.(messages.Serializable))
+
+		for size := 1; size < len(frame); size++ {
+			_, err := Read(bytes.NewReader(frame[:size]))
+			if err != io.ErrUnexpectedEOF {
+				t.Errorf(
+					"%s: frame truncated to %d bytes: expected %v, got %v",
+					line,
+					size,
+					io.ErrUnexpectedEOF,
+					err,
+				)
+			}
+		}
+	}
+}
+
+func TestRead_Empty(t *testing.T) {
+	_, err := Read(bytes.NewReader(nil))
+	if err != io.EOF {
+		t.Errorf("expected %v, got %v", io.EOF, err)
+	}
+}
+
+func TestRead_TooLarge(t *testing.T) {
+	header := make([]byte, HeaderSize)
+
+	binary.BigEndian.PutUint32(header, MaxFrameSize+1)
+
+	_, err := Read(bytes.NewReader(header))
+	if err == nil {
+		t.Errorf("expected error for frame larger than %d", MaxFrameSize)
+	}
+}
+
+func TestParse_Malformed(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", []byte{}},
+		{"truncated tag", []byte{3, 'p', 'u'}},
+		{"truncated arg name", []byte{2, 'o', 'k', 4, 'n', 'a'}},
+		{"missing value type", []byte{2, 'o', 'k', 1, 'x'}},
+		{"unknown value type", []byte{2, 'o', 'k', 1, 'x', 'z'}},
+		{"missing int", []byte{2, 'o', 'k', 1, 'x', TypeInt}},
+		{"truncated int", []byte{2, 'o', 'k', 1, 'x', TypeInt, 0x80}},
+		{"truncated color", []byte{2, 'o', 'k', 1, 'x', TypeColor, 1, 2}},
+		{"missing string length", []byte{2, 'o', 'k', 1, 'x', TypeString}},
+		{"truncated string", []byte{2, 'o', 'k', 1, 'x', TypeString, 3, 'a'}},
+		{"unknown tag", []byte{3, 'f', 'o', 'o'}},
+		{"wrong arg type", []byte{3, 'p', 'u', 't', 1, 'x', TypeString, 0}},
+	}
+
+	for _, test := range tests {
+		_, err := Parse(test.data)
+		if err == nil {
+			t.Errorf("%s: expected error", test.name)
+		}
+	}
+}
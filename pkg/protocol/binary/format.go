@@ -0,0 +1,32 @@
+package binary
+
+// Every binary message is sent as a frame:
+//
+//	+--------+-----+------+-----+------+-----+
+//	| length | tag | arg1 | ... | argN | ... |
+//	+--------+-----+------+-----+------+-----+
+//
+// - length: uint32 (big endian), size of frame without length itself;
+// - tag: uint8 length followed by tag bytes;
+// - arg: uint8 name length, name bytes, uint8 type and encoded value.
+//
+// Values are encoded according to their type:
+// - int: signed varint;
+// - color: 4 bytes in R, G, B, A order;
+// - string: unsigned varint length followed by string bytes;
+// - bool: no value, presence of argument means true.
+const (
+	TypeInt    = 'i'
+	TypeColor  = 'c'
+	TypeString = 's'
+	TypeBool   = 'b'
+)
+
+const (
+	// HeaderSize is a size of frame length prefix.
+	HeaderSize = 4
+
+	// MaxFrameSize limits size of single frame, so misbehaving client will
+	// not be able to exhaust memory.
+	MaxFrameSize = 16 * 1024 * 1024
+)
@@ -0,0 +1,139 @@
+package binary
+
+import (
+	"encoding/binary"
+	"fmt"
+	"image/color"
+	"io"
+
+	"github.com/seletskiy/mainframe/pkg/protocol/messages"
+	"github.com/seletskiy/mainframe/pkg/protocol/text"
+)
+
+// Read reads single frame from given reader and returns frame body without
+// length prefix.
+func Read(reader io.Reader) ([]byte, error) {
+	header := make([]byte, HeaderSize)
+
+	_, err := io.ReadFull(reader, header)
+	if err != nil {
+		return nil, err
+	}
+
+	length := binary.BigEndian.Uint32(header)
+	if length > MaxFrameSize {
+		return nil, fmt.Errorf(
+			"frame is too large: %d bytes (max %d)",
+			length,
+			MaxFrameSize,
+		)
+	}
+
+	data := make([]byte, length)
+
+	_, err = io.ReadFull(reader, data)
+	if err != nil {
+		// Connection closed after header is a truncated frame, not a
+		// clean end of stream.
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// Parse decodes message from frame body, which can be obtained via Read.
+func Parse(data []byte) (messages.Tagged, error) {
+	var (
+		tag  string
+		args = map[string]interface{}{}
+		err  error
+	)
+
+	tag, data, err = parseString8(data)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode message tag: %s", err)
+	}
+
+	for len(data) > 0 {
+		var (
+			name  string
+			value interface{}
+		)
+
+		name, data, err = parseString8(data)
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode arg name: %s", err)
+		}
+
+		value, data, err = parseValue(data)
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode arg %q: %s", name, err)
+		}
+
+		args[name] = value
+	}
+
+	return text.Unmarshal(tag, args)
+}
+
+func parseValue(data []byte) (interface{}, []byte, error) {
+	if len(data) < 1 {
+		return nil, nil, io.ErrUnexpectedEOF
+	}
+
+	kind, data := data[0], data[1:]
+
+	switch kind {
+	case TypeBool:
+		return true, data, nil
+
+	case TypeInt:
+		value, size := binary.Varint(data)
+		if size <= 0 {
+			return nil, nil, fmt.Errorf("malformed int value")
+		}
+
+		return int(value), data[size:], nil
+
+	case TypeColor:
+		if len(data) < 4 {
+			return nil, nil, io.ErrUnexpectedEOF
+		}
+
+		return color.RGBA{data[0], data[1], data[2], data[3]}, data[4:], nil
+
+	case TypeString:
+		length, size := binary.Uvarint(data)
+		if size <= 0 {
+			return nil, nil, fmt.Errorf("malformed string length")
+		}
+
+		data = data[size:]
+
+		if uint64(len(data)) < length {
+			return nil, nil, io.ErrUnexpectedEOF
+		}
+
+		return string(data[:length]), data[length:], nil
+
+	default:
+		return nil, nil, fmt.Errorf("unknown value type: 0x%02x", kind)
+	}
+}
+
+func parseString8(data []byte) (string, []byte, error) {
+	if len(data) < 1 {
+		return "", nil, io.ErrUnexpectedEOF
+	}
+
+	length := int(data[0])
+	if len(data) < 1+length {
+		return "", nil, io.ErrUnexpectedEOF
+	}
+
+	return string(data[1 : 1+length]), data[1+length:], nil
+}
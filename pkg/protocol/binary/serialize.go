@@ -0,0 +1,117 @@
+package binary
+
+import (
+	"encoding/binary"
+	"fmt"
+	"image/color"
+
+	"github.com/seletskiy/mainframe/pkg/protocol/messages"
+)
+
+func Serialize(message messages.Serializable) []byte {
+	buffer := make([]byte, HeaderSize, 64)
+
+	buffer = appendString8(buffer, message.Tag())
+
+	for _, arg := range message.Serialize() {
+		buffer = serializeArg(buffer, arg.Name, arg.Value)
+	}
+
+	binary.BigEndian.PutUint32(buffer, uint32(len(buffer)-HeaderSize))
+
+	return buffer
+}
+
+func serializeArg(buffer []byte, name string, value interface{}) []byte {
+	switch value := value.(type) {
+	case *bool:
+		if value == nil {
+			return buffer
+		}
+
+		return serializeArg(buffer, name, *value)
+
+	case *int64:
+		if value == nil {
+			return buffer
+		}
+
+		return serializeArg(buffer, name, *value)
+
+	case *int:
+		if value == nil {
+			return buffer
+		}
+
+		return serializeArg(buffer, name, *value)
+
+	case *color.RGBA:
+		if value == nil {
+			return buffer
+		}
+
+		return serializeArg(buffer, name, *value)
+
+	case *string:
+		if value == nil {
+			return buffer
+		}
+
+		return serializeArg(buffer, name, *value)
+
+	case *messages.Color:
+		if value == nil {
+			return buffer
+		}
+
+		return serializeArg(buffer, name, value.Value())
+
+	case bool:
+		if !value {
+			return buffer
+		}
+
+		buffer = appendString8(buffer, name)
+
+		return append(buffer, TypeBool)
+
+	case int64:
+		buffer = appendString8(buffer, name)
+		buffer = append(buffer, TypeInt)
+
+		return binary.AppendVarint(buffer, value)
+
+	case int:
+		return serializeArg(buffer, name, int64(value))
+
+	case color.RGBA:
+		buffer = appendString8(buffer, name)
+
+		return append(buffer, TypeColor, value.R, value.G, value.B, value.A)
+
+	case string:
+		buffer = appendString8(buffer, name)
+		buffer = append(buffer, TypeString)
+		buffer = binary.AppendUvarint(buffer, uint64(len(value)))
+
+		return append(buffer, value...)
+
+	default:
+		panic(
+			fmt.Sprintf(
+				"unsupported argument type: %[1]T (%[1]v)",
+				value,
+			),
+		)
+	}
+}
+
+func appendString8(buffer []byte, value string) []byte {
+	if len(value) > 0xff {
+		panic(fmt.Sprintf("identifier is too long: %q", value))
+	}
+
+	buffer = append(buffer, uint8(len(value)))
+
+	return append(buffer, value...)
+}
@@ -0,0 +1,13 @@
+package messages
+
+type Abort struct {
+	Identity
+}
+
+func (*Abort) Tag() string {
+	return "abort"
+}
+
+func (message *Abort) Serialize() []Arg {
+	return message.Identity.Serialize()
+}
@@ -0,0 +1,13 @@
+package messages
+
+type Begin struct {
+	Identity
+}
+
+func (*Begin) Tag() string {
+	return "begin"
+}
+
+func (message *Begin) Serialize() []Arg {
+	return message.Identity.Serialize()
+}
@@ -0,0 +1,35 @@
+package messages
+
+// Capture renders region of window to PNG image.
+type Capture struct {
+	Identity
+
+	X *int
+	Y *int
+
+	Rows    *int
+	Columns *int
+
+	// Path is file image is written to. Image is returned in reply if path
+	// is not specified.
+	Path *string
+
+	// Overwrite allows to replace existing file at Path.
+	Overwrite bool
+}
+
+func (*Capture) Tag() string {
+	return "capture"
+}
+
+func (message *Capture) Serialize() []Arg {
+	return append(
+		message.Identity.Serialize(),
+		Arg{"x", message.X},
+		Arg{"y", message.Y},
+		Arg{"rows", message.Rows},
+		Arg{"columns", message.Columns},
+		Arg{"path", message.Path},
+		Arg{"overwrite", message.Overwrite},
+	)
+}
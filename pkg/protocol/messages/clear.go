@@ -1,11 +1,15 @@
 package messages
 
 type Clear struct {
+	Identity
+
 	X *int
 	Y *int
 
 	Rows    *int
 	Columns *int
+
+	Tick *int
 }
 
 func (*Clear) Tag() string {
@@ -13,10 +17,12 @@ func (*Clear) Tag() string {
 }
 
 func (message *Clear) Serialize() []Arg {
-	return []Arg{
-		{"x", message.X},
-		{"y", message.Y},
-		{"rows", message.Rows},
-		{"columns", message.Columns},
-	}
+	return append(
+		message.Identity.Serialize(),
+		Arg{"x", message.X},
+		Arg{"y", message.Y},
+		Arg{"rows", message.Rows},
+		Arg{"columns", message.Columns},
+		Arg{"tick", message.Tick},
+	)
 }
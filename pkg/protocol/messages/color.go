@@ -0,0 +1,40 @@
+package messages
+
+import (
+	"image/color"
+)
+
+const PaletteSize = 256
+
+// Color is either RGBA color or index of color in window palette.
+//
+// Cells which color is set by palette index will be recolored when palette
+// entry is changed.
+type Color struct {
+	RGBA color.RGBA
+
+	Index   int
+	Indexed bool
+}
+
+func NewIndexedColor(index int) *Color {
+	return &Color{
+		Index:   index,
+		Indexed: true,
+	}
+}
+
+func NewRGBAColor(rgba color.RGBA) *Color {
+	return &Color{
+		RGBA: rgba,
+	}
+}
+
+// Value returns either palette index or RGBA color, depending on color kind.
+func (color *Color) Value() interface{} {
+	if color.Indexed {
+		return color.Index
+	}
+
+	return color.RGBA
+}
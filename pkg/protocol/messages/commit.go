@@ -0,0 +1,13 @@
+package messages
+
+type Commit struct {
+	Identity
+}
+
+func (*Commit) Tag() string {
+	return "commit"
+}
+
+func (message *Commit) Serialize() []Arg {
+	return message.Identity.Serialize()
+}
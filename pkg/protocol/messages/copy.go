@@ -0,0 +1,35 @@
+package messages
+
+// Copy copies rectangular region of cells to another position on the same
+// screen.
+type Copy struct {
+	Identity
+
+	X *int
+	Y *int
+
+	Rows    *int
+	Columns *int
+
+	ToX int
+	ToY int
+
+	Tick *int
+}
+
+func (*Copy) Tag() string {
+	return "copy"
+}
+
+func (message *Copy) Serialize() []Arg {
+	return append(
+		message.Identity.Serialize(),
+		Arg{"x", message.X},
+		Arg{"y", message.Y},
+		Arg{"rows", message.Rows},
+		Arg{"columns", message.Columns},
+		Arg{"to_x", message.ToX},
+		Arg{"to_y", message.ToY},
+		Arg{"tick", message.Tick},
+	)
+}
@@ -1,6 +1,8 @@
 package messages
 
 type Error struct {
+	Identity
+
 	Message string
 }
 
@@ -13,7 +15,8 @@ func (*Error) Tag() string {
 }
 
 func (message *Error) Serialize() []Arg {
-	return []Arg{
-		{"message", message.Message},
-	}
+	return append(
+		message.Identity.Serialize(),
+		Arg{"message", message.Message},
+	)
 }
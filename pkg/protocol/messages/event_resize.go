@@ -8,6 +8,10 @@ type EventResize struct {
 
 	Columns int
 	Rows    int
+
+	// Scale is content scale of monitor window is displayed on, in
+	// percents.
+	Scale int
 }
 
 func (message *EventResize) Serialize() []Arg {
@@ -17,5 +21,6 @@ func (message *EventResize) Serialize() []Arg {
 		Arg{"rows", message.Rows},
 		Arg{"width", message.Width},
 		Arg{"height", message.Height},
+		Arg{"scale", message.Scale},
 	)
 }
@@ -1,9 +1,21 @@
 package messages
 
 type Get struct {
+	Identity
+
 	Font struct {
 		Set bool
 	}
+
+	Cells struct {
+		Set bool
+
+		X *int
+		Y *int
+
+		Rows    *int
+		Columns *int
+	}
 }
 
 func (message *Get) Tag() string {
@@ -11,11 +23,22 @@ func (message *Get) Tag() string {
 }
 
 func (message *Get) Serialize() []Arg {
-	args := []Arg{}
+	args := message.Identity.Serialize()
 
 	if message.Font.Set {
 		args = append(args, Arg{"font", true})
 	}
 
+	if message.Cells.Set {
+		args = append(
+			args,
+			Arg{"cells", true},
+			Arg{"x", message.Cells.X},
+			Arg{"y", message.Cells.Y},
+			Arg{"rows", message.Cells.Rows},
+			Arg{"columns", message.Cells.Columns},
+		)
+	}
+
 	return args
 }
@@ -0,0 +1,29 @@
+package messages
+
+// Identified is implemented by commands and replies, which can carry optional
+// request ID.
+//
+// Client may specify ID in command and the same ID will be set in reply to
+// that command, so replies to pipelined commands can be matched.
+type Identified interface {
+	GetID() *int
+	SetID(id *int)
+}
+
+type Identity struct {
+	ID *int
+}
+
+func (identity *Identity) GetID() *int {
+	return identity.ID
+}
+
+func (identity *Identity) SetID(id *int) {
+	identity.ID = id
+}
+
+func (identity *Identity) Serialize() []Arg {
+	return []Arg{
+		{"id", identity.ID},
+	}
+}
@@ -0,0 +1,11 @@
+package messages
+
+// Move is the same as Copy, but cells of source region which are not
+// overwritten by copied cells are cleared afterwards.
+type Move struct {
+	Copy
+}
+
+func (*Move) Tag() string {
+	return "move"
+}
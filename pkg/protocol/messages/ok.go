@@ -1,6 +1,8 @@
 package messages
 
 type OK struct {
+	Identity
+
 	Args []Arg
 }
 
@@ -9,7 +11,7 @@ func (*OK) Tag() string {
 }
 
 func (message *OK) Serialize() []Arg {
-	return message.Args
+	return append(message.Identity.Serialize(), message.Args...)
 }
 
 func (message *OK) Set(name string, value interface{}) *OK {
@@ -1,6 +1,12 @@
 package messages
 
+import (
+	"image/color"
+)
+
 type Open struct {
+	Identity
+
 	*Size
 
 	X *int
@@ -13,6 +19,19 @@ type Open struct {
 	Fixed    bool
 	Bare     bool
 	Floating bool
+
+	Transparent bool
+	Zoom        bool
+
+	Foreground *color.RGBA
+	Background *color.RGBA
+
+	Font     *string
+	FontSize *int
+	FontDPI  *int
+
+	LineHeight    *int
+	LetterSpacing *int
 }
 
 func (Open) Tag() string {
@@ -20,16 +39,26 @@ func (Open) Tag() string {
 }
 
 func (message *Open) Serialize() []Arg {
-	args := []Arg{
-		{"x", message.X},
-		{"y", message.Y},
-		{"title", message.Title},
-		{"raw", message.Raw},
-		{"hidden", message.Hidden},
-		{"fixed", message.Fixed},
-		{"bare", message.Bare},
-		{"floating", message.Floating},
-	}
+	args := append(
+		message.Identity.Serialize(),
+		Arg{"x", message.X},
+		Arg{"y", message.Y},
+		Arg{"title", message.Title},
+		Arg{"raw", message.Raw},
+		Arg{"hidden", message.Hidden},
+		Arg{"fixed", message.Fixed},
+		Arg{"bare", message.Bare},
+		Arg{"floating", message.Floating},
+		Arg{"transparent", message.Transparent},
+		Arg{"zoom", message.Zoom},
+		Arg{"fg", message.Foreground},
+		Arg{"bg", message.Background},
+		Arg{"font", message.Font},
+		Arg{"font_size", message.FontSize},
+		Arg{"font_dpi", message.FontDPI},
+		Arg{"line_height", message.LineHeight},
+		Arg{"letter_spacing", message.LetterSpacing},
+	)
 
 	if message.Size != nil {
 		args = append(args, message.Size.Serialize()...)
@@ -0,0 +1,23 @@
+package messages
+
+const (
+	ProtocolText   = "text"
+	ProtocolBinary = "binary"
+)
+
+type Protocol struct {
+	Identity
+
+	Kind string
+}
+
+func (*Protocol) Tag() string {
+	return "protocol"
+}
+
+func (message *Protocol) Serialize() []Arg {
+	return append(
+		message.Identity.Serialize(),
+		Arg{"kind", message.Kind},
+	)
+}
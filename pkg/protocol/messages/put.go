@@ -1,20 +1,25 @@
 package messages
 
-import (
-	"image/color"
-)
-
 type Put struct {
+	Identity
+
 	X int
 	Y int
 
 	Columns *int
 	Rows    *int
 
-	Foreground *color.RGBA
-	Background *color.RGBA
+	Foreground *Color
+	Background *Color
 	Text       *string
 
+	Bold      bool
+	Italic    bool
+	Underline bool
+	Strike    bool
+	Reverse   bool
+	Dim       bool
+
 	Tick      *int
 	Exclusive bool
 }
@@ -24,15 +29,32 @@ func (message *Put) Tag() string {
 }
 
 func (message *Put) Serialize() []Arg {
-	return []Arg{
-		{"x", message.X},
-		{"y", message.Y},
-		{"columns", message.Columns},
-		{"rows", message.Rows},
-		{"fg", message.Foreground},
-		{"bg", message.Background},
-		{"text", message.Text},
-		{"tick", message.Tick},
-		{"exclusive", message.Exclusive},
-	}
+	return append(
+		message.Identity.Serialize(),
+		Arg{"x", message.X},
+		Arg{"y", message.Y},
+		Arg{"columns", message.Columns},
+		Arg{"rows", message.Rows},
+		Arg{"fg", message.Foreground},
+		Arg{"bg", message.Background},
+		Arg{"text", message.Text},
+		Arg{"bold", message.Bold},
+		Arg{"italic", message.Italic},
+		Arg{"underline", message.Underline},
+		Arg{"strike", message.Strike},
+		Arg{"reverse", message.Reverse},
+		Arg{"dim", message.Dim},
+		Arg{"tick", message.Tick},
+		Arg{"exclusive", message.Exclusive},
+	)
+}
+
+// IsStyled returns true if any of style flags is set.
+func (message *Put) IsStyled() bool {
+	return message.Bold ||
+		message.Italic ||
+		message.Underline ||
+		message.Strike ||
+		message.Reverse ||
+		message.Dim
 }
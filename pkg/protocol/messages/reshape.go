@@ -1,6 +1,8 @@
 package messages
 
 type Reshape struct {
+	Identity
+
 	*Size
 
 	X *int
@@ -12,10 +14,11 @@ func (message *Reshape) Tag() string {
 }
 
 func (message *Reshape) Serialize() []Arg {
-	args := []Arg{
-		{"x", message.X},
-		{"y", message.Y},
-	}
+	args := append(
+		message.Identity.Serialize(),
+		Arg{"x", message.X},
+		Arg{"y", message.Y},
+	)
 
 	if message.Size != nil {
 		args = append(args, message.Size.Serialize()...)
@@ -0,0 +1,41 @@
+package messages
+
+import (
+	"image/color"
+)
+
+type Set struct {
+	Identity
+
+	Foreground *color.RGBA
+	Background *color.RGBA
+
+	Palette *int
+	Color   *color.RGBA
+
+	Font     *string
+	FontSize *int
+	FontDPI  *int
+
+	LineHeight    *int
+	LetterSpacing *int
+}
+
+func (*Set) Tag() string {
+	return "set"
+}
+
+func (message *Set) Serialize() []Arg {
+	return append(
+		message.Identity.Serialize(),
+		Arg{"fg", message.Foreground},
+		Arg{"bg", message.Background},
+		Arg{"palette", message.Palette},
+		Arg{"color", message.Color},
+		Arg{"font", message.Font},
+		Arg{"font_size", message.FontSize},
+		Arg{"font_dpi", message.FontDPI},
+		Arg{"line_height", message.LineHeight},
+		Arg{"letter_spacing", message.LetterSpacing},
+	)
+}
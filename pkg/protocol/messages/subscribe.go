@@ -1,6 +1,8 @@
 package messages
 
 type Subscribe struct {
+	Identity
+
 	Resize   bool
 	Keyboard bool
 	Input    bool
@@ -9,3 +11,12 @@ type Subscribe struct {
 func (message *Subscribe) Tag() string {
 	return "subscribe"
 }
+
+func (message *Subscribe) Serialize() []Arg {
+	return append(
+		message.Identity.Serialize(),
+		Arg{"resize", message.Resize},
+		Arg{"keyboard", message.Keyboard},
+		Arg{"input", message.Input},
+	)
+}
@@ -2,6 +2,8 @@ package text
 
 import (
 	"fmt"
+
+	"github.com/seletskiy/mainframe/pkg/protocol/messages"
 )
 
 type ErrMissingArg string
@@ -34,6 +36,20 @@ func (err ErrIncorrectType) Error() string {
 	)
 }
 
+type ErrPaletteIndex struct {
+	Arg   string
+	Index int
+}
+
+func (err ErrPaletteIndex) Error() string {
+	return fmt.Sprintf(
+		"palette index for argument %q is out of range: %d (expected 0..%d)",
+		err.Arg,
+		err.Index,
+		messages.PaletteSize-1,
+	)
+}
+
 type ErrUnknownArg string
 
 func (arg ErrUnknownArg) Error() string {
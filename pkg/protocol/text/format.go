@@ -8,8 +8,8 @@ import (
 var (
 	reTag = regexp.MustCompile(`(?P<tag>[a-z_]+)`)
 
-	reValueInt    = regexp.MustCompile(`(?P<int>\d+)`)
-	reValueColor  = regexp.MustCompile(`#(?P<color>[\da-f]{3}|[\da-f]{6})`)
+	reValueInt    = regexp.MustCompile(`(?P<int>-?\d+)`)
+	reValueColor  = regexp.MustCompile(`#(?P<color>[\da-f]{8}|[\da-f]{6}|[\da-f]{4}|[\da-f]{3})`)
 	reValueString = regexp.MustCompile(`(?P<string>"(?:\\.|[^\\"])*")`)
 
 	reValue = regexp.MustCompile(
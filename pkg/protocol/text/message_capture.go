@@ -0,0 +1,53 @@
+package text
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/seletskiy/mainframe/pkg/protocol/messages"
+)
+
+func parseCaptureMessage(
+	args map[string]interface{},
+) (messages.Tagged, error) {
+	message := &messages.Capture{}
+
+	switch {
+	case args["x"] != nil && args["y"] == nil:
+		fallthrough
+	case args["x"] == nil && args["y"] != nil:
+		return nil, fmt.Errorf("x & y should be specified together")
+	}
+
+	err := NewSpec().
+		Int("x", &message.X).
+		Int("y", &message.Y).
+		Int("columns", &message.Columns).
+		Int("rows", &message.Rows).
+		String("path", &message.Path).
+		Bool("overwrite", &message.Overwrite).
+		Bind(args)
+	if err != nil {
+		return nil, err
+	}
+
+	if message.Columns != nil && *message.Columns <= 0 {
+		return nil, fmt.Errorf("columns should be greater than zero")
+	}
+
+	if message.Rows != nil && *message.Rows <= 0 {
+		return nil, fmt.Errorf("rows should be greater than zero")
+	}
+
+	// Image is written by mainframe process, which working directory is
+	// unknown to client.
+	if message.Path != nil && !filepath.IsAbs(*message.Path) {
+		return nil, fmt.Errorf("path should be absolute")
+	}
+
+	if message.Overwrite && message.Path == nil {
+		return nil, fmt.Errorf("overwrite should be specified along with path")
+	}
+
+	return message, nil
+}
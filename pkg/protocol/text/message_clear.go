@@ -23,10 +23,19 @@ func parseClearMessage(
 		Int("y", &message.Y).
 		Int("columns", &message.Columns).
 		Int("rows", &message.Rows).
+		Int("tick", &message.Tick).
 		Bind(args)
 	if err != nil {
 		return nil, err
 	}
 
+	if message.Columns != nil && *message.Columns <= 0 {
+		return nil, fmt.Errorf("columns should be greater than zero")
+	}
+
+	if message.Rows != nil && *message.Rows <= 0 {
+		return nil, fmt.Errorf("rows should be greater than zero")
+	}
+
 	return message, nil
 }
@@ -0,0 +1,18 @@
+package text
+
+import (
+	"github.com/seletskiy/mainframe/pkg/protocol/messages"
+)
+
+func parseCommitMessage(
+	args map[string]interface{},
+) (messages.Tagged, error) {
+	message := &messages.Commit{}
+
+	err := NewSpec().Bind(args)
+	if err != nil {
+		return nil, err
+	}
+
+	return message, nil
+}
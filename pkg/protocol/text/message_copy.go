@@ -0,0 +1,69 @@
+package text
+
+import (
+	"fmt"
+
+	"github.com/seletskiy/mainframe/pkg/protocol/messages"
+)
+
+func parseCopyMessage(
+	args map[string]interface{},
+) (messages.Tagged, error) {
+	message := &messages.Copy{}
+
+	err := parseCopyArgs(args, message)
+	if err != nil {
+		return nil, err
+	}
+
+	return message, nil
+}
+
+func parseMoveMessage(
+	args map[string]interface{},
+) (messages.Tagged, error) {
+	message := &messages.Move{}
+
+	err := parseCopyArgs(args, &message.Copy)
+	if err != nil {
+		return nil, err
+	}
+
+	return message, nil
+}
+
+// parseCopyArgs parses arguments which are common for copy and move
+// messages.
+func parseCopyArgs(args map[string]interface{}, message *messages.Copy) error {
+	switch {
+	case args["x"] != nil && args["y"] == nil:
+		fallthrough
+	case args["x"] == nil && args["y"] != nil:
+		return fmt.Errorf("x & y should be specified together")
+	}
+
+	err := NewSpec().
+		Int("x", &message.X).
+		Int("y", &message.Y).
+		Int("columns", &message.Columns).
+		Int("rows", &message.Rows).
+		Int("to_x", &message.ToX).
+		Int("to_y", &message.ToY).
+		Int("tick", &message.Tick).
+		Require("to_x").
+		Require("to_y").
+		Bind(args)
+	if err != nil {
+		return err
+	}
+
+	if message.Columns != nil && *message.Columns <= 0 {
+		return fmt.Errorf("columns should be greater than zero")
+	}
+
+	if message.Rows != nil && *message.Rows <= 0 {
+		return fmt.Errorf("rows should be greater than zero")
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package text
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/seletskiy/mainframe/pkg/protocol/messages"
+)
+
+func parseEventMessage(
+	args map[string]interface{},
+) (messages.Tagged, error) {
+	event := messages.Event{}
+
+	// Tick can be omitted in events which are sent to mainframe as
+	// synthetic input, because tick of window is used instead.
+	spec := NewSpec().
+		Require("kind").
+		Int("tick", &event.Tick).
+		String("kind", &event.Kind)
+
+	switch args["kind"] {
+	case "resize":
+		message := &messages.EventResize{}
+
+		err := spec.
+			Int("columns", &message.Columns).
+			Int("rows", &message.Rows).
+			Int("width", &message.Width).
+			Int("height", &message.Height).
+			Int("scale", &message.Scale).
+			Bind(args)
+		if err != nil {
+			return nil, err
+		}
+
+		message.Event = event
+
+		return message, nil
+
+	case "keyboard":
+		message := &messages.EventKeyboard{}
+
+		err := spec.
+			String("symbol", &message.Symbol).
+			Int("code", &message.Code).
+			Bool("press", &message.Press).
+			Bool("release", &message.Release).
+			Bool("repeat", &message.Repeat).
+			Bool("shift", &message.Shift).
+			Bool("ctrl", &message.Ctrl).
+			Bool("alt", &message.Alt).
+			Bool("super", &message.Super).
+			Bind(args)
+		if err != nil {
+			return nil, err
+		}
+
+		message.Event = event
+
+		return message, nil
+
+	case "input":
+		message := &messages.EventInput{}
+
+		var char string
+
+		err := spec.
+			Require("char").
+			String("char", &char).
+			Bool("shift", &message.Shift).
+			Bool("ctrl", &message.Ctrl).
+			Bool("alt", &message.Alt).
+			Bool("super", &message.Super).
+			Bind(args)
+		if err != nil {
+			return nil, err
+		}
+
+		message.Event = event
+		message.Char, _ = utf8.DecodeRuneInString(char)
+
+		return message, nil
+
+	default:
+		return nil, fmt.Errorf("unknown event kind: %v", args["kind"])
+	}
+}
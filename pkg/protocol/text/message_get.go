@@ -14,6 +14,7 @@ func parseGetMessage(
 	err := NewSpec().
 		SkipUnknown().
 		Bool("font", &message.Font.Set).
+		Bool("cells", &message.Cells.Set).
 		Bind(args)
 	if err != nil {
 		return nil, err
@@ -22,6 +23,11 @@ func parseGetMessage(
 	switch {
 	case message.Font.Set:
 		//
+	case message.Cells.Set:
+		err = parseGetCellsArgs(args, message)
+		if err != nil {
+			return nil, err
+		}
 	default:
 		return nil, fmt.Errorf(
 			"no attribute specified for get",
@@ -30,3 +36,36 @@ func parseGetMessage(
 
 	return message, nil
 }
+
+func parseGetCellsArgs(
+	args map[string]interface{},
+	message *messages.Get,
+) error {
+	switch {
+	case args["x"] != nil && args["y"] == nil:
+		fallthrough
+	case args["x"] == nil && args["y"] != nil:
+		return fmt.Errorf("x & y should be specified together")
+	}
+
+	err := NewSpec().
+		Skip("cells").
+		Int("x", &message.Cells.X).
+		Int("y", &message.Cells.Y).
+		Int("columns", &message.Cells.Columns).
+		Int("rows", &message.Cells.Rows).
+		Bind(args)
+	if err != nil {
+		return err
+	}
+
+	if message.Cells.Columns != nil && *message.Cells.Columns <= 0 {
+		return fmt.Errorf("columns should be greater than zero")
+	}
+
+	if message.Cells.Rows != nil && *message.Cells.Rows <= 0 {
+		return fmt.Errorf("rows should be greater than zero")
+	}
+
+	return nil
+}
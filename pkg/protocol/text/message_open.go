@@ -38,10 +38,28 @@ func parseOpenMessage(
 		Bool("fixed", &message.Fixed).
 		Bool("bare", &message.Bare).
 		Bool("floating", &message.Floating).
+		Bool("transparent", &message.Transparent).
+		Bool("zoom", &message.Zoom).
+		Color("fg", &message.Foreground).
+		Color("bg", &message.Background).
+		String("font", &message.Font).
+		Int("font_size", &message.FontSize).
+		Int("font_dpi", &message.FontDPI).
+		Int("line_height", &message.LineHeight).
+		Int("letter_spacing", &message.LetterSpacing).
 		Bind(args)
 	if err != nil {
 		return nil, err
 	}
 
+	err = validateFontArgs(
+		message.FontSize,
+		message.FontDPI,
+		message.LineHeight,
+	)
+	if err != nil {
+		return nil, err
+	}
+
 	return message, nil
 }
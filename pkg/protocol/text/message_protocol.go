@@ -0,0 +1,30 @@
+package text
+
+import (
+	"fmt"
+
+	"github.com/seletskiy/mainframe/pkg/protocol/messages"
+)
+
+func parseProtocolMessage(
+	args map[string]interface{},
+) (messages.Tagged, error) {
+	message := &messages.Protocol{}
+
+	err := NewSpec().
+		Require("kind").
+		String("kind", &message.Kind).
+		Bind(args)
+	if err != nil {
+		return nil, err
+	}
+
+	switch message.Kind {
+	case messages.ProtocolText:
+	case messages.ProtocolBinary:
+	default:
+		return nil, fmt.Errorf("unknown protocol kind: %q", message.Kind)
+	}
+
+	return message, nil
+}
@@ -1,6 +1,8 @@
 package text
 
 import (
+	"fmt"
+
 	"github.com/seletskiy/mainframe/pkg/protocol/messages"
 )
 
@@ -11,8 +13,17 @@ func parsePutMessage(
 	case args["fg"] != nil:
 	case args["bg"] != nil:
 	case args["text"] != nil:
+	case args["bold"] != nil:
+	case args["italic"] != nil:
+	case args["underline"] != nil:
+	case args["strike"] != nil:
+	case args["reverse"] != nil:
+	case args["dim"] != nil:
 	default:
-		return nil, ErrMissingGroup{"fg", "bg", "text"}
+		return nil, ErrMissingGroup{
+			"fg", "bg", "text",
+			"bold", "italic", "underline", "strike", "reverse", "dim",
+		}
 	}
 
 	message := &messages.Put{}
@@ -24,9 +35,15 @@ func parsePutMessage(
 		Int("y", &message.Y).
 		Int("columns", &message.Columns).
 		Int("rows", &message.Rows).
-		Color("fg", &message.Foreground).
-		Color("bg", &message.Background).
+		IndexedColor("fg", &message.Foreground).
+		IndexedColor("bg", &message.Background).
 		String("text", &message.Text).
+		Bool("bold", &message.Bold).
+		Bool("italic", &message.Italic).
+		Bool("underline", &message.Underline).
+		Bool("strike", &message.Strike).
+		Bool("reverse", &message.Reverse).
+		Bool("dim", &message.Dim).
 		Int("tick", &message.Tick).
 		Bool("exclusive", &message.Exclusive).
 		Bind(args)
@@ -34,5 +51,13 @@ func parsePutMessage(
 		return nil, err
 	}
 
+	if message.Columns != nil && *message.Columns <= 0 {
+		return nil, fmt.Errorf("columns should be greater than zero")
+	}
+
+	if message.Rows != nil && *message.Rows <= 0 {
+		return nil, fmt.Errorf("rows should be greater than zero")
+	}
+
 	return message, nil
 }
@@ -28,11 +28,16 @@ func parseReshapeMessage(
 	switch {
 	case args["x"] != nil && args["y"] != nil:
 	case args["width"] != nil && args["height"] != nil:
+	case args["columns"] != nil && args["rows"] != nil:
 	default:
-		return nil, fmt.Errorf("x & y or width & height should be specified")
+		return nil, fmt.Errorf(
+			"x & y, width & height or columns & rows should be specified",
+		)
 	}
 
 	err = NewSpec().
+		Skip("width").
+		Skip("height").
 		Skip("rows").
 		Skip("columns").
 		Int("x", &message.X).
@@ -0,0 +1,84 @@
+package text
+
+import (
+	"fmt"
+
+	"github.com/seletskiy/mainframe/pkg/protocol/messages"
+)
+
+func parseSetMessage(
+	args map[string]interface{},
+) (messages.Tagged, error) {
+	switch {
+	case args["palette"] != nil && args["color"] == nil:
+		fallthrough
+	case args["palette"] == nil && args["color"] != nil:
+		return nil, fmt.Errorf("palette and color should be specified together")
+	}
+
+	message := &messages.Set{}
+
+	err := NewSpec().
+		Color("fg", &message.Foreground).
+		Color("bg", &message.Background).
+		Int("palette", &message.Palette).
+		Color("color", &message.Color).
+		String("font", &message.Font).
+		Int("font_size", &message.FontSize).
+		Int("font_dpi", &message.FontDPI).
+		Int("line_height", &message.LineHeight).
+		Int("letter_spacing", &message.LetterSpacing).
+		Bind(args)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case message.Foreground != nil:
+	case message.Background != nil:
+	case message.Palette != nil:
+	case message.Font != nil:
+	case message.FontSize != nil:
+	case message.FontDPI != nil:
+	case message.LineHeight != nil:
+	case message.LetterSpacing != nil:
+	default:
+		return nil, ErrMissingGroup{
+			"fg", "bg", "palette", "font", "font_size", "font_dpi",
+			"line_height", "letter_spacing",
+		}
+	}
+
+	err = validateFontArgs(
+		message.FontSize,
+		message.FontDPI,
+		message.LineHeight,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if message.Palette != nil {
+		if *message.Palette < 0 || *message.Palette >= messages.PaletteSize {
+			return nil, ErrPaletteIndex{"palette", *message.Palette}
+		}
+	}
+
+	return message, nil
+}
+
+func validateFontArgs(size *int, dpi *int, lineHeight *int) error {
+	if size != nil && *size <= 0 {
+		return fmt.Errorf("font_size should be greater than zero")
+	}
+
+	if dpi != nil && *dpi <= 0 {
+		return fmt.Errorf("font_dpi should be greater than zero")
+	}
+
+	if lineHeight != nil && *lineHeight <= 0 {
+		return fmt.Errorf("line_height should be greater than zero")
+	}
+
+	return nil
+}
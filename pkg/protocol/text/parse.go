@@ -55,22 +55,86 @@ func Parse(data string) (messages.Tagged, error) {
 		}
 	}
 
+	return Unmarshal(tag, args)
+}
+
+// Unmarshal builds message with given tag from already decoded arguments.
+//
+// It is shared between text and binary protocols, so both produce exactly
+// same messages.
+func Unmarshal(
+	tag string,
+	args map[string]interface{},
+) (messages.Tagged, error) {
 	parsers := map[string]ParseFunc{
 		"ok":        parseOKMessage,
 		"error":     parseErrorMessage,
+		"event":     parseEventMessage,
 		"put":       parsePutMessage,
 		"subscribe": parseSubscribeMessage,
 		"open":      parseOpenMessage,
 		"reshape":   parseReshapeMessage,
 		"clear":     parseClearMessage,
+		"copy":      parseCopyMessage,
+		"move":      parseMoveMessage,
+		"capture":   parseCaptureMessage,
 		"get":       parseGetMessage,
+		"protocol":  parseProtocolMessage,
+		"begin":     parseBeginMessage,
+		"commit":    parseCommitMessage,
+		"abort":     parseAbortMessage,
+		"set":       parseSetMessage,
+	}
+
+	// Request ID is common for all commands and replies, so it's handled
+	// here instead of every parser.
+	id, err := parseID(args)
+	if err != nil {
+		return nil, err
+	}
+
+	parser, ok := parsers[tag]
+	if !ok {
+		return nil, &messages.Error{
+			Identity: messages.Identity{ID: id},
+			Message:  fmt.Sprintf(`unknown message tag: %s`, tag),
+		}
+	}
+
+	message, err := parser(args)
+	if err != nil {
+		return nil, &messages.Error{
+			Identity: messages.Identity{ID: id},
+			Message:  err.Error(),
+		}
 	}
 
-	if parser, ok := parsers[tag]; ok {
-		return parser(args)
-	} else {
-		return nil, fmt.Errorf(`unknown message tag: %s`, tag)
+	if id != nil {
+		identified, ok := message.(messages.Identified)
+		if !ok {
+			return nil, ErrUnknownArg("id")
+		}
+
+		identified.SetID(id)
 	}
+
+	return message, nil
+}
+
+func parseID(args map[string]interface{}) (*int, error) {
+	var id *int
+
+	err := NewSpec().
+		SkipUnknown().
+		Int("id", &id).
+		Bind(args)
+	if err != nil {
+		return nil, err
+	}
+
+	delete(args, "id")
+
+	return id, nil
 }
 
 func tokenize(data string) []map[string]string {
@@ -98,9 +162,9 @@ func parseColor(data string) color.RGBA {
 	var step int
 
 	switch len(data) {
-	case 3:
+	case 3, 4:
 		step = 1
-	case 6:
+	case 6, 8:
 		step = 2
 	}
 
@@ -108,16 +172,26 @@ func parseColor(data string) color.RGBA {
 	g, _ := strconv.ParseInt(data[1*step:2*step], 16, 0)
 	b, _ := strconv.ParseInt(data[2*step:3*step], 16, 0)
 
+	// Alpha is optional and color is opaque by default.
+	a := int64(0xff)
+	if len(data) == 4*step {
+		a, _ = strconv.ParseInt(data[3*step:4*step], 16, 0)
+	}
+
 	if step == 1 {
 		r = r + r<<4
 		g = g + g<<4
 		b = b + b<<4
+
+		if len(data) == 4 {
+			a = a + a<<4
+		}
 	}
 
 	return color.RGBA{
 		uint8(r),
 		uint8(g),
 		uint8(b),
-		0xff,
+		uint8(a),
 	}
 }
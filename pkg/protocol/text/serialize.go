@@ -64,6 +64,13 @@ func serializeValue(value interface{}) (string, bool) {
 
 		return serializeValue(*value)
 
+	case *messages.Color:
+		if value == nil {
+			return "", false
+		}
+
+		return serializeValue(value.Value())
+
 	case bool:
 		if value {
 			return "true", true
@@ -94,21 +101,21 @@ func serializeValue(value interface{}) (string, bool) {
 }
 
 func serializeColor(color color.RGBA) string {
-	r := serializeHex(color.R>>8) + serializeHex(color.R&0xf)
-	g := serializeHex(color.G>>8) + serializeHex(color.G&0xf)
-	b := serializeHex(color.B>>8) + serializeHex(color.B&0xf)
+	value := "#" +
+		serializeHex(color.R) +
+		serializeHex(color.G) +
+		serializeHex(color.B)
+
+	// Alpha is omitted for opaque colors.
+	if color.A != 0xff {
+		value += serializeHex(color.A)
+	}
 
-	return "#" + r + g + b
+	return value
 }
 
 func serializeHex(value uint8) string {
-	if value >= 0 && value <= 9 {
-		return string('0' + value)
-	}
-
-	if value >= 10 && value <= 15 {
-		return string('a' + value)
-	}
+	const digits = "0123456789abcdef"
 
-	return "?"
+	return string([]byte{digits[value>>4], digits[value&0xf]})
 }
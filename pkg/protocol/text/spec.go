@@ -3,6 +3,8 @@ package text
 import (
 	"fmt"
 	"image/color"
+
+	"github.com/seletskiy/mainframe/pkg/protocol/messages"
 )
 
 type Spec struct {
@@ -38,6 +40,14 @@ func (spec *Spec) Color(arg string, out interface{}) *Spec {
 	return spec
 }
 
+// IndexedColor binds argument which can be either color or palette index.
+func (spec *Spec) IndexedColor(arg string, out interface{}) *Spec {
+	spec.Types[arg] = "indexed_color"
+	spec.Bound[arg] = out
+
+	return spec
+}
+
 func (spec *Spec) String(arg string, out interface{}) *Spec {
 	spec.Types[arg] = "string"
 	spec.Bound[arg] = out
@@ -103,6 +113,17 @@ func (spec *Spec) Bind(args map[string]interface{}) error {
 			value, ok = args[name].(int)
 		case "color":
 			value, ok = args[name].(color.RGBA)
+		case "indexed_color":
+			switch arg := args[name].(type) {
+			case int:
+				if arg < 0 || arg >= messages.PaletteSize {
+					return ErrPaletteIndex{name, arg}
+				}
+
+				value, ok = messages.NewIndexedColor(arg), true
+			case color.RGBA:
+				value, ok = messages.NewRGBAColor(arg), true
+			}
 		case "string":
 			value, ok = args[name].(string)
 		case "bool":
@@ -129,6 +150,8 @@ func assign(value interface{}, out interface{}) {
 	switch out := out.(type) {
 	case *int:
 		*out = value.(int)
+	case *int64:
+		*out = int64(value.(int))
 	case *color.RGBA:
 		*out = value.(color.RGBA)
 	case *string:
@@ -142,6 +165,8 @@ func assign(value interface{}, out interface{}) {
 	case **color.RGBA:
 		value := value.(color.RGBA)
 		*out = &value
+	case **messages.Color:
+		*out = value.(*messages.Color)
 	case **string:
 		value := value.(string)
 		*out = &value